@@ -3,13 +3,21 @@ package notch
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/peterbourgon/ff/v2/ffcli"
 	"github.com/runetale/notch/engine"
+	"github.com/runetale/notch/engine/confirm"
+	"github.com/runetale/notch/engine/debugger"
+	"github.com/runetale/notch/engine/simulator"
 	"github.com/runetale/notch/llm"
 	"github.com/runetale/notch/task"
 )
@@ -26,6 +34,34 @@ var notchArgs struct {
 	strategy      string
 	forceFormat   bool
 	saveTo        string
+	confirm       string
+	confirmAddr   string
+	confirmHook   string
+	confirmWait   time.Duration
+	maxTime       time.Duration
+	maxTokens     uint64
+	maxCost       float64
+	maxActions    string
+	operatorSock  string
+	operatorStdin bool
+	summarize     bool
+	summaryGen    string
+	concurrency   uint
+	onError       string
+	step          bool
+	dryRun        string
+	dryRunReads   bool
+	planExecute   bool
+	reflectAfter  uint
+	reflectEvery  uint
+	reasoningHist string
+	reasoningOut  string
+	fsRoot        string
+	httpAllow     string
+	embedder      string
+	memoryKeys    uint
+	memoryBank    string
+	shellSession  bool
 }
 
 type StrategyFormat string
@@ -49,6 +85,34 @@ var NotchCmd = &ffcli.Command{
 		fs.StringVar(&notchArgs.strategy, "S", string(XML), "if a supported format is specified, that format is used")
 		fs.BoolVar(&notchArgs.forceFormat, "F", false, "use the fomat specified in serialisation, even if native tools are supported")
 		fs.StringVar(&notchArgs.saveTo, "save", "", "at each step, the current system prompts and status data are stored in this file")
+		fs.StringVar(&notchArgs.confirm, "confirm", string(confirm.STDIN), "how to confirm actions, stdin, approve, deny or http")
+		fs.StringVar(&notchArgs.confirmAddr, "confirm-addr", "127.0.0.1:7777", "listen address of the approval endpoint, if -confirm=http")
+		fs.StringVar(&notchArgs.confirmHook, "confirm-webhook", "", "post pending invocations to this url, if -confirm=http")
+		fs.DurationVar(&notchArgs.confirmWait, "confirm-timeout", 5*time.Minute, "deny the invocation if no decision is made within this time, if -confirm=http")
+		fs.DurationVar(&notchArgs.maxTime, "max-time", 0, "stop the run after this wall-clock time, 0 is the no limit")
+		fs.Uint64Var(&notchArgs.maxTokens, "max-tokens", 0, "stop the run after this number of llm tokens, 0 is the no limit")
+		fs.Float64Var(&notchArgs.maxCost, "max-cost", 0, "stop the run after this estimated spend in USD, 0 is the no limit")
+		fs.StringVar(&notchArgs.maxActions, "max-actions", "", "maximum executions per action, e.g. shell=20,delegate_task=3")
+		fs.StringVar(&notchArgs.operatorSock, "operator-socket", "", "unix socket path, each line sent to it is given to the model as operator guidance")
+		fs.BoolVar(&notchArgs.operatorStdin, "operator-stdin", false, "read operator guidance from stdin, requires -confirm other than stdin")
+		fs.BoolVar(&notchArgs.summarize, "summarize", false, "condense the executions evicted from the history into a running summary")
+		fs.StringVar(&notchArgs.summaryGen, "summary-generator", "", "generator string used for the summary, the main generator if empty")
+		fs.UintVar(&notchArgs.concurrency, "concurrency", 0, "parallel safe invocations executed at once, 0 uses the task value")
+		fs.StringVar(&notchArgs.onError, "on-error", "", "what to do with the remaining invocations after one fails, stop, continue or skip_dependent, empty uses the task value")
+		fs.BoolVar(&notchArgs.step, "step", false, "pause before every llm call and every invocation, commands are read from stdin")
+		fs.StringVar(&notchArgs.dryRun, "dry-run", "", "do not execute actions with side effects, their results are canned, stdin or model simulated")
+		fs.BoolVar(&notchArgs.dryRunReads, "dry-run-reads", false, "simulate read only actions too, if -dry-run is set")
+		fs.BoolVar(&notchArgs.planExecute, "plan", false, "plan and execute mode, the model writes a plan first then works on one step at a time, requires the planning namespace")
+		fs.UintVar(&notchArgs.reflectAfter, "reflect-after", 0, "run a critique turn after this number of consecutive failed executions, 0 uses the task value")
+		fs.UintVar(&notchArgs.reflectEvery, "reflect-every", 0, "run a critique turn every this number of steps, 0 uses the task value")
+		fs.StringVar(&notchArgs.reasoningHist, "reasoning-history", "", "reasoning sent back to the model, none, last or all, empty uses the task value")
+		fs.StringVar(&notchArgs.reasoningOut, "reasoning-export", "", "append the reasoning of every response to this json lines file")
+		fs.StringVar(&notchArgs.fsRoot, "fs-root", "", "root directory of the filesystem namespace, the task value or the current directory if empty")
+		fs.StringVar(&notchArgs.httpAllow, "http-allow", "", "comma separated hosts, *.domain wildcards or CIDR ranges the http namespace can reach, the task value if empty")
+		fs.StringVar(&notchArgs.embedder, "embedder", "", "embedding model of the rag namespace, e.g. openai://text-embedding-3-small, the task value if empty, bm25 ranking if both are empty")
+		fs.UintVar(&notchArgs.memoryKeys, "memory-keys-after", 0, "show only the keys of the memories in the prompt past this number of memories, 0 uses the task value")
+		fs.StringVar(&notchArgs.memoryBank, "memory-bank", "", "keep the memories between runs in this named bank, the task value if empty")
+		fs.BoolVar(&notchArgs.shellSession, "shell-session", false, "run the shell commands in persistent sessions keeping the directory and the environment")
 		return fs
 	})(),
 	Exec: exec,
@@ -66,8 +130,6 @@ func exec(ctx context.Context, args []string) error {
 		return err
 	}
 
-	// TODO: add embedder for RAG
-
 	// setup task
 	tasklet, err := task.GetFromPath(notchArgs.taskpath)
 	if err != nil {
@@ -81,8 +143,130 @@ func exec(ctx context.Context, args []string) error {
 
 	log.Printf("notch v%s > 🧬 %s %s", version, notchArgs.generator, tasklet.GetName())
 
+	// without an embedder the rag namespace ranks the documents with bm25
+	embedderGenerator := tasklet.GetRag().Embedder
+	if notchArgs.embedder != "" {
+		embedderGenerator = notchArgs.embedder
+	}
+	if embedderGenerator != "" {
+		embedder, err := llm.NewEmbedder(embedderGenerator, notchArgs.apiKey)
+		if err != nil {
+			return err
+		}
+		tasklet.SetEmbedder(embedder)
+	}
+
+	budget, err := parseBudget()
+	if err != nil {
+		return err
+	}
+	tasklet.SetBudget(budget)
+
+	if notchArgs.concurrency > 0 {
+		tasklet.SetConcurrency(notchArgs.concurrency)
+	}
+
+	if notchArgs.onError != "" {
+		policy := task.ErrorPolicy(notchArgs.onError)
+		switch policy {
+		case task.ERROR_STOP, task.ERROR_CONTINUE, task.ERROR_SKIP_DEPENDENT:
+			tasklet.SetErrorPolicy(policy)
+		default:
+			return fmt.Errorf("unknown error policy %s", notchArgs.onError)
+		}
+	}
+
+	if notchArgs.dryRun != "" {
+		switch simulator.SimulatorType(notchArgs.dryRun) {
+		case simulator.CANNED, simulator.STDIN, simulator.MODEL:
+			tasklet.SetDryRun(notchArgs.dryRun, notchArgs.dryRunReads)
+		default:
+			return fmt.Errorf("unknown dry run simulation %s", notchArgs.dryRun)
+		}
+	}
+
+	if notchArgs.reasoningHist != "" || notchArgs.reasoningOut != "" {
+		policy := task.ReasoningPolicy(notchArgs.reasoningHist)
+		switch policy {
+		case "", task.REASONING_NONE, task.REASONING_LAST, task.REASONING_ALL:
+			tasklet.SetReasoning(policy, notchArgs.reasoningOut)
+		default:
+			return fmt.Errorf("unknown reasoning history policy %s", notchArgs.reasoningHist)
+		}
+	}
+
+	if notchArgs.memoryKeys > 0 {
+		tasklet.SetMemoryKeysOnlyAfter(int(notchArgs.memoryKeys))
+	}
+
+	if notchArgs.memoryBank != "" {
+		tasklet.SetMemoryBank(notchArgs.memoryBank)
+	}
+
+	if notchArgs.shellSession {
+		tasklet.SetShellPersistent(true)
+	}
+
+	if notchArgs.fsRoot != "" {
+		tasklet.SetFilesystemRoot(notchArgs.fsRoot)
+	}
+
+	if notchArgs.httpAllow != "" {
+		allow := []string{}
+		for _, host := range strings.Split(notchArgs.httpAllow, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				allow = append(allow, host)
+			}
+		}
+		tasklet.SetHTTPAllow(allow)
+	}
+
+	if notchArgs.reflectAfter > 0 || notchArgs.reflectEvery > 0 {
+		tasklet.SetReflection(notchArgs.reflectAfter, notchArgs.reflectEvery)
+	}
+
+	if notchArgs.planExecute {
+		tasklet.SetPlanExecute()
+	}
+
+	if notchArgs.summarize || notchArgs.summaryGen != "" {
+		tasklet.SetSummary(notchArgs.summaryGen)
+	}
+
+	confirmer, err := newConfirmer(confirm.ConfirmerType(notchArgs.confirm), tasklet)
+	if err != nil {
+		return err
+	}
+
 	_, nativeTool := strategyDesicion(StrategyFormat(notchArgs.strategy), notchArgs.forceFormat, factory)
-	e := engine.NewEngine(tasklet, factory, uint(notchArgs.maxIterations), nativeTool, notchArgs.saveTo)
+	e := engine.NewEngine(tasklet, factory, uint(notchArgs.maxIterations), nativeTool, notchArgs.saveTo, confirmer)
+
+	if notchArgs.operatorStdin && tasklet.GetDryRun().Enable && simulator.SimulatorType(tasklet.GetDryRun().Simulate) == simulator.STDIN {
+		return fmt.Errorf("-operator-stdin can't be used with stdin dry run simulation")
+	}
+
+	// step mode on the terminal
+	if notchArgs.step {
+		if notchArgs.operatorStdin {
+			return fmt.Errorf("-step can't be used with -operator-stdin")
+		}
+		e.Debug(debugger.NewDebugger(tasklet.GetUserInput, os.Stdout))
+	}
+
+	// operator messages during the run
+	if notchArgs.operatorStdin {
+		if confirm.ConfirmerType(notchArgs.confirm) == confirm.STDIN {
+			return fmt.Errorf("-operator-stdin can't be used with -confirm=%s", confirm.STDIN)
+		}
+		go e.Operator().ReadLines(os.Stdin)
+	}
+	if notchArgs.operatorSock != "" {
+		sock, err := e.Operator().ListenUnix(notchArgs.operatorSock)
+		if err != nil {
+			return err
+		}
+		defer sock.Close()
+	}
 
 	// start
 	go e.Start()
@@ -98,15 +282,65 @@ func exec(ctx context.Context, args []string) error {
 				e.Stop()
 			case <-e.Done():
 				ch <- struct{}{}
-				log.Printf("shutdown completed notch")
+				log.Printf("shutdown completed notch, %s", e.Result().Display())
+				if tasklet.GetDryRun().Enable {
+					log.Printf("dry run, %d actions were not executed", len(e.DryRunPlan()))
+					for i, inv := range e.DryRunPlan() {
+						log.Printf("  %d. %s", i+1, inv)
+					}
+				}
 			}
 		}
 	}()
 	<-ch
 
+	if closer, ok := confirmer.(io.Closer); ok {
+		closer.Close()
+	}
+
 	return nil
 }
 
+// budget flags override the task budget
+func parseBudget() (task.Budget, error) {
+	budget := task.Budget{
+		Time:   notchArgs.maxTime,
+		Tokens: notchArgs.maxTokens,
+		Cost:   notchArgs.maxCost,
+	}
+
+	if notchArgs.maxActions == "" {
+		return budget, nil
+	}
+	budget.Actions = make(map[string]uint, 0)
+	for _, pair := range strings.Split(notchArgs.maxActions, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return budget, fmt.Errorf("invalid -max-actions '%s', expected name=count", pair)
+		}
+		max, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return budget, fmt.Errorf("invalid -max-actions count for %s: %v", name, err)
+		}
+		budget.Actions[name] = uint(max)
+	}
+	return budget, nil
+}
+
+func newConfirmer(t confirm.ConfirmerType, tasklet *task.Task) (confirm.Confirmer, error) {
+	switch t {
+	case confirm.STDIN:
+		return confirm.NewStdinConfirmer(tasklet.GetUserInput), nil
+	case confirm.APPROVE:
+		return confirm.NewAutoConfirmer(true), nil
+	case confirm.DENY:
+		return confirm.NewAutoConfirmer(false), nil
+	case confirm.HTTP:
+		return confirm.NewHTTPConfirmer(notchArgs.confirmAddr, notchArgs.confirmHook, notchArgs.confirmWait)
+	}
+	return nil, fmt.Errorf("unknown confirm type %s", t)
+}
+
 func strategyDesicion(strategy StrategyFormat, forceFormat bool, factory *llm.LLMFactory) (StrategyFormat, bool) {
 	if forceFormat {
 		log.Printf("using configured serialization strategy %s\n", strategy)
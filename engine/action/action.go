@@ -7,6 +7,17 @@ import (
 	"github.com/runetale/notch/types"
 )
 
+type Effect string
+
+const (
+	// only the storages of the engine
+	INTERNAL Effect = "internal"
+	// reads outside the engine without changing anything
+	READ Effect = "read"
+	// changes something outside the engine
+	WRITE Effect = "write"
+)
+
 // all namespace's implement this interfaces
 type Action interface {
 	GetNamespace() types.NamespaceType
@@ -19,6 +30,10 @@ type Action interface {
 	Timeout() *time.Duration
 	RequiredVariables() []*string // retrieved when variables such as `$SSH_HOST` are set
 	RequiresUserConfirmation() bool
+	// true if the action has no side effects and can run concurrently with other parallel safe actions
+	ParallelSafe() bool
+	// what the action touches, dry runs only simulate reads and writes outside the engine
+	Effect() Effect
 	ExamplePayload() *string
 	ExampleAttributes() map[string]string
 }
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed delegate.prompt
+var delegatePrompt string
+
+//go:embed ns.prompt
+var nsPrompt string
+
+// runs a child engine to completion and returns its final report.
+// set by the engine, the action package can't import the engine
+type Spawner func(goal string, namespaces []string, maxSteps uint) string
+
+type Delegate struct {
+	spawner Spawner
+}
+
+func NewDelegate() action.Action {
+	return &Delegate{}
+}
+
+func (d *Delegate) SetSpawner(spawner Spawner) {
+	d.spawner = spawner
+}
+
+func (d *Delegate) Name() string {
+	return "delegate_task"
+}
+
+func (d *Delegate) Description() string {
+	return delegatePrompt
+}
+
+func (d *Delegate) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	if d.spawner == nil {
+		return "sub agents are not available"
+	}
+
+	goal := strings.TrimSpace(payload)
+	if goal == "" {
+		return "a goal is required for the sub agent"
+	}
+
+	maxSteps, err := strconv.ParseUint(strings.TrimSpace(attributes["max_steps"]), 10, 32)
+	if err != nil || maxSteps == 0 {
+		return fmt.Sprintf("invalid max_steps '%s', must be a positive number", attributes["max_steps"])
+	}
+
+	namespaces := []string{}
+	for _, ns := range strings.Split(attributes["namespaces"], ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" && ns != "*" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return d.spawner(goal, namespaces, uint(maxSteps))
+}
+
+func (d *Delegate) Timeout() *time.Duration {
+	return nil
+}
+
+func (d *Delegate) ExamplePayload() *string {
+	p := "enumerate the open ports of 10.0.0.5 and save them to your memories"
+	return &p
+}
+
+func (d *Delegate) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["namespaces"] = "shell,memory"
+	attr["max_steps"] = "10"
+	return attr
+}
+
+func (d *Delegate) RequiredVariables() []*string {
+	return nil
+}
+
+func (d *Delegate) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (d *Delegate) ParallelSafe() bool {
+	return false
+}
+
+func (d *Delegate) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (d *Delegate) GetNamespace() types.NamespaceType {
+	return types.AGENT
+}
+
+func (d *Delegate) NamespaceDescription() string {
+	return nsPrompt
+}
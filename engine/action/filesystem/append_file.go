@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed append.prompt
+var appendPrompt string
+
+type AppendFile struct {
+	sandbox *Sandbox
+}
+
+func NewAppendFile(sandbox *Sandbox) action.Action {
+	return &AppendFile{
+		sandbox: sandbox,
+	}
+}
+
+func (a *AppendFile) Name() string {
+	return "append_file"
+}
+
+func (a *AppendFile) Description() string {
+	return appendPrompt
+}
+
+func (a *AppendFile) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := a.sandbox.Resolve(attributes["path"])
+	if err != nil {
+		return err.Error()
+	}
+
+	size, err := a.sandbox.write(path, payload, true)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%d bytes appended, %s is %d bytes", len(payload), a.sandbox.Rel(path), size)
+}
+
+func (a *AppendFile) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *AppendFile) ExamplePayload() *string {
+	p := "10.0.0.6 http 80/tcp"
+	return &p
+}
+
+func (a *AppendFile) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["path"] = "notes/hosts.txt"
+	return attr
+}
+
+func (a *AppendFile) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *AppendFile) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *AppendFile) ParallelSafe() bool {
+	return false
+}
+
+func (a *AppendFile) Effect() action.Effect {
+	return action.WRITE
+}
+
+func (a *AppendFile) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (a *AppendFile) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,86 @@
+package filesystem
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed delete.prompt
+var deletePrompt string
+
+type Delete struct {
+	sandbox *Sandbox
+}
+
+func NewDelete(sandbox *Sandbox) action.Action {
+	return &Delete{
+		sandbox: sandbox,
+	}
+}
+
+func (d *Delete) Name() string {
+	return "delete_path"
+}
+
+func (d *Delete) Description() string {
+	return deletePrompt
+}
+
+func (d *Delete) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := d.sandbox.Resolve(payload)
+	if err != nil {
+		return err.Error()
+	}
+	if path == d.sandbox.Root() {
+		return "the root directory can not be deleted"
+	}
+
+	// not recursive, directories must be emptied first
+	if err := os.Remove(path); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s deleted", d.sandbox.Rel(path))
+}
+
+func (d *Delete) Timeout() *time.Duration {
+	return nil
+}
+
+func (d *Delete) ExamplePayload() *string {
+	p := "notes/old.txt"
+	return &p
+}
+
+func (d *Delete) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (d *Delete) RequiredVariables() []*string {
+	return nil
+}
+
+func (d *Delete) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (d *Delete) ParallelSafe() bool {
+	return false
+}
+
+func (d *Delete) Effect() action.Effect {
+	return action.WRITE
+}
+
+func (d *Delete) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (d *Delete) NamespaceDescription() string {
+	return nsPrompt
+}
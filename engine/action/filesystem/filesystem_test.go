@@ -0,0 +1,133 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newSandbox(t *testing.T, maxRead, maxWrite int64) *Sandbox {
+	root := t.TempDir()
+	s, err := NewSandbox(root, maxRead, maxWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func Test_Resolve(t *testing.T) {
+	s := newSandbox(t, 1024, 1024)
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(s.Root(), "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(s.Root(), "inside"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(s.Root(), "inside"), filepath.Join(s.Root(), "link")); err != nil {
+		t.Fatal(err)
+	}
+	// links to paths that do not exist yet
+	if err := os.Symlink(filepath.Join(outside, "created.txt"), filepath.Join(s.Root(), "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("inside/new.txt", filepath.Join(s.Root(), "relative")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(s.Root(), "dangling_dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path string
+		ok   bool
+	}{
+		{"notes.txt", true},
+		{"new/dir/notes.txt", true},
+		{s.Root() + "/notes.txt", true},
+		{"link/notes.txt", true},
+		{"../notes.txt", false},
+		{"inside/../../notes.txt", false},
+		{"/etc/passwd", false},
+		{"escape/notes.txt", false},
+		{"escape/missing/notes.txt", false},
+		{"dangling", false},
+		{"dangling_dir/notes.txt", false},
+		{"relative", true},
+		{"", false},
+	}
+	for _, c := range cases {
+		_, err := s.Resolve(c.path)
+		if (err == nil) != c.ok {
+			t.Fatalf("%s: unexpected error %v", c.path, err)
+		}
+	}
+
+	out := NewWriteFile(s).Run(nil, map[string]string{"path": "dangling"}, "pwned")
+	if _, err := os.Stat(filepath.Join(outside, "created.txt")); err == nil {
+		t.Fatalf("file created outside of the root: %s", out)
+	}
+}
+
+func Test_ReadWrite(t *testing.T) {
+	s := newSandbox(t, 16, 32)
+	write, appendFile, read := NewWriteFile(s), NewAppendFile(s), NewReadFile(s)
+
+	out := write.Run(nil, map[string]string{"path": "a/b.txt"}, "one\ntwo\nthree\n")
+	if !strings.Contains(out, "14 bytes written to a/b.txt") {
+		t.Fatalf("unexpected output %s", out)
+	}
+	if out := read.Run(nil, map[string]string{"lines": "2-3"}, "a/b.txt"); out != "two\nthree\n" {
+		t.Fatalf("unexpected range %q", out)
+	}
+	if out := read.Run(nil, map[string]string{"lines": "3-"}, "a/b.txt"); out != "three\n" {
+		t.Fatalf("unexpected range %q", out)
+	}
+	if out := read.Run(nil, map[string]string{"lines": "9"}, "a/b.txt"); out != "a/b.txt has 3 lines" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// size limits
+	if out := appendFile.Run(nil, map[string]string{"path": "a/b.txt"}, "four\nfive\nsix\n"); !strings.Contains(out, "a/b.txt is 28 bytes") {
+		t.Fatalf("unexpected output %s", out)
+	}
+	if out := appendFile.Run(nil, map[string]string{"path": "a/b.txt"}, "seven\n"); !strings.Contains(out, "the limit is 32") {
+		t.Fatalf("write limit not enforced %s", out)
+	}
+	if out := read.Run(nil, map[string]string{"lines": "all"}, "a/b.txt"); !strings.HasPrefix(out, "one\ntwo\nthree\nfo\n... (truncated") {
+		t.Fatalf("read limit not enforced %q", out)
+	}
+}
+
+func Test_ListGlobStatDelete(t *testing.T) {
+	s := newSandbox(t, 1024, 1024)
+	write := NewWriteFile(s)
+	write.Run(nil, map[string]string{"path": "etc/app.conf"}, "a")
+	write.Run(nil, map[string]string{"path": "etc/nginx/site.conf"}, "b")
+	write.Run(nil, map[string]string{"path": "notes.txt"}, "c")
+
+	if out := NewListDir(s).Run(nil, nil, "."); out != "etc/\nnotes.txt 1 bytes\n" {
+		t.Fatalf("unexpected listing %q", out)
+	}
+	if out := NewGlob(s).Run(nil, nil, "**/*.conf"); out != "etc/app.conf\netc/nginx/site.conf" {
+		t.Fatalf("unexpected matches %q", out)
+	}
+	if out := NewGlob(s).Run(nil, nil, "etc/*.conf"); out != "etc/app.conf" {
+		t.Fatalf("unexpected matches %q", out)
+	}
+	if out := NewStat(s).Run(nil, nil, "etc"); !strings.HasPrefix(out, "etc: directory") {
+		t.Fatalf("unexpected stat %q", out)
+	}
+
+	del := NewDelete(s)
+	if out := del.Run(nil, nil, "etc"); !strings.Contains(out, "not empty") {
+		t.Fatalf("directories must be empty %q", out)
+	}
+	if out := del.Run(nil, nil, "notes.txt"); out != "notes.txt deleted" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if out := del.Run(nil, nil, "."); !strings.Contains(out, "can not be deleted") {
+		t.Fatalf("the root must not be deleted %q", out)
+	}
+}
@@ -0,0 +1,142 @@
+package filesystem
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed glob.prompt
+var globPrompt string
+
+type Glob struct {
+	sandbox *Sandbox
+}
+
+func NewGlob(sandbox *Sandbox) action.Action {
+	return &Glob{
+		sandbox: sandbox,
+	}
+}
+
+func (g *Glob) Name() string {
+	return "glob"
+}
+
+func (g *Glob) Description() string {
+	return globPrompt
+}
+
+// longer results are truncated
+const maxMatches = 500
+
+var errEnough = errors.New("enough matches")
+
+func (g *Glob) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	pattern := strings.Trim(filepath.ToSlash(strings.TrimSpace(payload)), "/")
+	if pattern == "" {
+		return "no pattern specified"
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Sprintf("invalid pattern '%s': %s", pattern, err.Error())
+	}
+
+	matches := []string{}
+	err := filepath.WalkDir(g.sandbox.Root(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable directories are skipped
+			return nil
+		}
+		rel := g.sandbox.Rel(p)
+		if rel == "." {
+			return nil
+		}
+		if match(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			if len(matches) == maxMatches {
+				return errEnough
+			}
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		return err.Error()
+	}
+
+	if len(matches) == 0 {
+		return fmt.Sprintf("no path matches '%s'", pattern)
+	}
+	result := strings.Join(matches, "\n")
+	if errors.Is(err, errEnough) {
+		result += fmt.Sprintf("\n... (more than %d matches, use a narrower pattern)", maxMatches)
+	}
+	return result
+}
+
+// matches the path segments, ** matches zero or more segments
+func match(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if match(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return match(pattern[1:], segments[1:])
+}
+
+func (g *Glob) Timeout() *time.Duration {
+	return nil
+}
+
+func (g *Glob) ExamplePayload() *string {
+	p := "**/*.conf"
+	return &p
+}
+
+func (g *Glob) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (g *Glob) RequiredVariables() []*string {
+	return nil
+}
+
+func (g *Glob) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (g *Glob) ParallelSafe() bool {
+	return true
+}
+
+func (g *Glob) Effect() action.Effect {
+	return action.READ
+}
+
+func (g *Glob) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (g *Glob) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,109 @@
+package filesystem
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed list.prompt
+var listPrompt string
+
+type ListDir struct {
+	sandbox *Sandbox
+}
+
+func NewListDir(sandbox *Sandbox) action.Action {
+	return &ListDir{
+		sandbox: sandbox,
+	}
+}
+
+func (l *ListDir) Name() string {
+	return "list_dir"
+}
+
+func (l *ListDir) Description() string {
+	return listPrompt
+}
+
+// longer listings are truncated
+const maxEntries = 500
+
+func (l *ListDir) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := l.sandbox.Resolve(payload)
+	if err != nil {
+		return err.Error()
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err.Error()
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("%s is empty", l.sandbox.Rel(path))
+	}
+
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i == maxEntries {
+			sb.WriteString(fmt.Sprintf("... (%d more entries)\n", len(entries)-maxEntries))
+			break
+		}
+		info, err := entry.Info()
+		switch {
+		case err != nil:
+			sb.WriteString(fmt.Sprintf("%s (%s)\n", entry.Name(), err.Error()))
+		case entry.IsDir():
+			sb.WriteString(fmt.Sprintf("%s/\n", entry.Name()))
+		case entry.Type()&os.ModeSymlink != 0:
+			sb.WriteString(fmt.Sprintf("%s@\n", entry.Name()))
+		default:
+			sb.WriteString(fmt.Sprintf("%s %d bytes\n", entry.Name(), info.Size()))
+		}
+	}
+	return sb.String()
+}
+
+func (l *ListDir) Timeout() *time.Duration {
+	return nil
+}
+
+func (l *ListDir) ExamplePayload() *string {
+	p := "."
+	return &p
+}
+
+func (l *ListDir) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (l *ListDir) RequiredVariables() []*string {
+	return nil
+}
+
+func (l *ListDir) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (l *ListDir) ParallelSafe() bool {
+	return true
+}
+
+func (l *ListDir) Effect() action.Effect {
+	return action.READ
+}
+
+func (l *ListDir) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (l *ListDir) NamespaceDescription() string {
+	return nsPrompt
+}
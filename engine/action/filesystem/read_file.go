@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed read.prompt
+var readPrompt string
+
+//go:embed ns.prompt
+var nsPrompt string
+
+type ReadFile struct {
+	sandbox *Sandbox
+}
+
+func NewReadFile(sandbox *Sandbox) action.Action {
+	return &ReadFile{
+		sandbox: sandbox,
+	}
+}
+
+func (r *ReadFile) Name() string {
+	return "read_file"
+}
+
+func (r *ReadFile) Description() string {
+	return readPrompt
+}
+
+func (r *ReadFile) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := r.sandbox.Resolve(payload)
+	if err != nil {
+		return err.Error()
+	}
+	from, to, err := parseLines(attributes["lines"])
+	if err != nil {
+		return err.Error()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+
+	// lines are read until the range or the size limit ends
+	reader := bufio.NewReader(f)
+	var sb strings.Builder
+	line := 0
+	truncated := false
+	for {
+		text, err := reader.ReadString('\n')
+		if text != "" {
+			line++
+			if line >= from {
+				if remain := r.sandbox.maxRead - int64(sb.Len()); int64(len(text)) > remain {
+					sb.WriteString(text[:remain])
+					truncated = true
+					break
+				}
+				sb.WriteString(text)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return err.Error()
+			}
+			break
+		}
+		if to > 0 && line >= to {
+			break
+		}
+	}
+
+	if line < from {
+		return fmt.Sprintf("%s has %d lines", r.sandbox.Rel(path), line)
+	}
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n... (truncated at line %d after %d bytes, read the next lines with a range)", line, r.sandbox.maxRead))
+	}
+	return sb.String()
+}
+
+// all, N, N- or N-M, 1-based and inclusive. to is 0 for the end of the file
+func parseLines(lines string) (int, int, error) {
+	lines = strings.TrimSpace(lines)
+	if lines == "" || lines == "all" {
+		return 1, 0, nil
+	}
+
+	start, end, isRange := strings.Cut(lines, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("invalid lines '%s', expected all or a range such as 10-50", lines)
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	if strings.TrimSpace(end) == "" {
+		return from, 0, nil
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(end))
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid lines '%s', expected all or a range such as 10-50", lines)
+	}
+	return from, to, nil
+}
+
+func (r *ReadFile) Timeout() *time.Duration {
+	return nil
+}
+
+func (r *ReadFile) ExamplePayload() *string {
+	p := "notes/scan.txt"
+	return &p
+}
+
+func (r *ReadFile) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["lines"] = "all"
+	return attr
+}
+
+func (r *ReadFile) RequiredVariables() []*string {
+	return nil
+}
+
+func (r *ReadFile) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (r *ReadFile) ParallelSafe() bool {
+	return true
+}
+
+func (r *ReadFile) Effect() action.Effect {
+	return action.READ
+}
+
+func (r *ReadFile) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (r *ReadFile) NamespaceDescription() string {
+	return nsPrompt
+}
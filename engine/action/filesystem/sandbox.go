@@ -0,0 +1,154 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// every path used by the filesystem actions is confined to the root
+type Sandbox struct {
+	// absolute, without symlinks
+	root     string
+	maxRead  int64
+	maxWrite int64
+}
+
+func NewSandbox(root string, maxRead, maxWrite int64) (*Sandbox, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	return &Sandbox{
+		root:     real,
+		maxRead:  maxRead,
+		maxWrite: maxWrite,
+	}, nil
+}
+
+func (s *Sandbox) Root() string {
+	return s.root
+}
+
+// returns the real path of a relative or absolute path inside the root,
+// symlinks pointing outside of the root are rejected
+func (s *Sandbox) Resolve(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", errors.New("no path specified")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	path = filepath.Clean(path)
+	if !s.contains(path) {
+		return "", fmt.Errorf("%s is outside of the root %s", path, s.root)
+	}
+
+	real, err := evalExisting(path)
+	if err != nil {
+		return "", err
+	}
+	if !s.contains(real) {
+		return "", fmt.Errorf("%s escapes the root %s through a symlink", path, s.root)
+	}
+	return real, nil
+}
+
+// path relative to the root, for the outputs
+func (s *Sandbox) Rel(path string) string {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (s *Sandbox) contains(path string) bool {
+	return path == s.root || strings.HasPrefix(path, s.root+string(filepath.Separator))
+}
+
+// resolves the symlinks of the longest existing prefix, the missing part is kept as is.
+// a dangling symlink is followed to its target, a file created through it would be there
+func evalExisting(path string) (string, error) {
+	missing := []string{}
+	links := 0
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			if links++; links > 255 {
+				return "", fmt.Errorf("%s: too many links", path)
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			path = filepath.Clean(target)
+			continue
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// writes data to the file, or appends it, within the size limit
+func (s *Sandbox) write(path string, data string, append bool) (int64, error) {
+	size := int64(len(data))
+	if append {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	if s.maxWrite > 0 && size > s.maxWrite {
+		return 0, fmt.Errorf("the file would be %d bytes, the limit is %d", size, s.maxWrite)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	// the path was resolved, a symlink created since then is not followed
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC | syscall.O_NOFOLLOW
+	if append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND | syscall.O_NOFOLLOW
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
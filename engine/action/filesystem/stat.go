@@ -0,0 +1,93 @@
+package filesystem
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed stat.prompt
+var statPrompt string
+
+type Stat struct {
+	sandbox *Sandbox
+}
+
+func NewStat(sandbox *Sandbox) action.Action {
+	return &Stat{
+		sandbox: sandbox,
+	}
+}
+
+func (s *Stat) Name() string {
+	return "stat"
+}
+
+func (s *Stat) Description() string {
+	return statPrompt
+}
+
+func (s *Stat) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := s.sandbox.Resolve(payload)
+	if err != nil {
+		return err.Error()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err.Error()
+	}
+
+	kind := "file"
+	if info.IsDir() {
+		kind = "directory"
+	} else if !info.Mode().IsRegular() {
+		kind = "special file"
+	}
+	return fmt.Sprintf(
+		"%s: %s, %d bytes, mode %s, modified %s",
+		s.sandbox.Rel(path), kind, info.Size(), info.Mode().String(), info.ModTime().Format("2006-01-02 15:04:05"),
+	)
+}
+
+func (s *Stat) Timeout() *time.Duration {
+	return nil
+}
+
+func (s *Stat) ExamplePayload() *string {
+	p := "notes/hosts.txt"
+	return &p
+}
+
+func (s *Stat) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (s *Stat) RequiredVariables() []*string {
+	return nil
+}
+
+func (s *Stat) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (s *Stat) ParallelSafe() bool {
+	return true
+}
+
+func (s *Stat) Effect() action.Effect {
+	return action.READ
+}
+
+func (s *Stat) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (s *Stat) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed write.prompt
+var writePrompt string
+
+type WriteFile struct {
+	sandbox *Sandbox
+}
+
+func NewWriteFile(sandbox *Sandbox) action.Action {
+	return &WriteFile{
+		sandbox: sandbox,
+	}
+}
+
+func (w *WriteFile) Name() string {
+	return "write_file"
+}
+
+func (w *WriteFile) Description() string {
+	return writePrompt
+}
+
+func (w *WriteFile) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	path, err := w.sandbox.Resolve(attributes["path"])
+	if err != nil {
+		return err.Error()
+	}
+
+	size, err := w.sandbox.write(path, payload, false)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%d bytes written to %s", size, w.sandbox.Rel(path))
+}
+
+func (w *WriteFile) Timeout() *time.Duration {
+	return nil
+}
+
+func (w *WriteFile) ExamplePayload() *string {
+	p := "10.0.0.5 ssh 22/tcp"
+	return &p
+}
+
+func (w *WriteFile) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["path"] = "notes/hosts.txt"
+	return attr
+}
+
+func (w *WriteFile) RequiredVariables() []*string {
+	return nil
+}
+
+func (w *WriteFile) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (w *WriteFile) ParallelSafe() bool {
+	return false
+}
+
+func (w *WriteFile) Effect() action.Effect {
+	return action.WRITE
+}
+
+func (w *WriteFile) GetNamespace() types.NamespaceType {
+	return types.FILESYSTEM
+}
+
+func (w *WriteFile) NamespaceDescription() string {
+	return nsPrompt
+}
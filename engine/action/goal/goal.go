@@ -56,6 +56,14 @@ func (d *Goal) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (d *Goal) ParallelSafe() bool {
+	return false
+}
+
+func (d *Goal) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (d *Goal) GetNamespace() types.NamespaceType {
 	return types.GOAL
 }
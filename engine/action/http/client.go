@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxRedirects = 10
+
+// sends the requests of the namespace, only to the allowed hosts
+type Client struct {
+	client      *http.Client
+	allow       []string
+	maxResponse int
+}
+
+// allow holds host names, *.domain wildcards or CIDR ranges, every host is allowed if empty
+func NewClient(allow []string, maxResponse int, timeout time.Duration, insecure bool) *Client {
+	c := &Client{
+		allow:       allow,
+		maxResponse: maxResponse,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecure}
+	c.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// redirects are followed within the allowlist
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return c.checkHost(req.URL)
+		},
+	}
+	return c
+}
+
+func (c *Client) checkHost(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme '%s', use http or https", u.Scheme)
+	}
+	if len(c.allow) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+	for _, allowed := range c.allow {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		switch {
+		case allowed == host:
+			return nil
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]):
+			return nil
+		case ip != nil && strings.Contains(allowed, "/"):
+			if _, cidr, err := net.ParseCIDR(allowed); err == nil && cidr.Contains(ip) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("host %s is not in the allowlist", host)
+}
+
+// returns the response as text, the body is truncated to the maximum size
+func (c *Client) Do(req *http.Request) (*http.Response, string, error) {
+	if err := c.checkHost(req.URL); err != nil {
+		return nil, "", err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	body, err := decode(res)
+	if err != nil {
+		return res, "", err
+	}
+	// the rest of the body is neither read nor decompressed, the size is only known up to the limit
+	data, err := io.ReadAll(io.LimitReader(body, int64(c.maxResponse)+1))
+	if err != nil {
+		return res, "", err
+	}
+
+	return res, c.format(res, data), nil
+}
+
+// bodies are decoded by hand since the encoding is requested by the headers
+func decode(res *http.Response) (io.Reader, error) {
+	switch strings.ToLower(res.Header.Get("Content-Encoding")) {
+	case "gzip":
+		return gzip.NewReader(res.Body)
+	case "deflate":
+		// zlib wrapped or raw deflate, both are seen in the wild
+		r := bufio.NewReader(res.Body)
+		if header, err := r.Peek(2); err == nil && isZlib(header) {
+			return zlib.NewReader(r)
+		}
+		return flate.NewReader(r), nil
+	default:
+		return res.Body, nil
+	}
+}
+
+// compression method 8 and a valid header checksum
+func isZlib(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+func (c *Client) format(res *http.Response, data []byte) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s\n", res.Proto, res.Status))
+
+	names := make([]string, 0, len(res.Header))
+	for name := range res.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range res.Header[name] {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", name, value))
+		}
+	}
+	sb.WriteString("\n")
+
+	size := fmt.Sprintf("%d bytes", len(data))
+	truncated := len(data) > c.maxResponse
+	if truncated {
+		size = fmt.Sprintf("more than %d bytes", c.maxResponse)
+		data = data[:c.maxResponse]
+		// a character cut by the truncation is dropped
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+
+	if !utf8.Valid(data) {
+		sb.WriteString(fmt.Sprintf("(binary body of %s not shown)", size))
+		return sb.String()
+	}
+	sb.Write(data)
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n... (body of %s truncated, %d bytes shown)", size, len(data)))
+	}
+	return sb.String()
+}
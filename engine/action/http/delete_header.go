@@ -0,0 +1,76 @@
+package http
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed delete_header.prompt
+var deleteHeaderPrompt string
+
+type DeleteHeader struct {
+}
+
+func NewDeleteHeader() action.Action {
+	return &DeleteHeader{}
+}
+
+func (d *DeleteHeader) Name() string {
+	return "delete_http_header"
+}
+
+func (d *DeleteHeader) Description() string {
+	return deleteHeaderPrompt
+}
+
+func (d *DeleteHeader) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	name := strings.TrimSpace(payload)
+	if _, found := storage.GetEntry(name); !found {
+		return fmt.Sprintf("header %s is not set", name)
+	}
+	storage.DelTagged(name)
+	return fmt.Sprintf("header %s deleted", name)
+}
+
+func (d *DeleteHeader) Timeout() *time.Duration {
+	return nil
+}
+
+func (d *DeleteHeader) ExamplePayload() *string {
+	p := "Cookie"
+	return &p
+}
+
+func (d *DeleteHeader) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (d *DeleteHeader) RequiredVariables() []*string {
+	return nil
+}
+
+func (d *DeleteHeader) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (d *DeleteHeader) ParallelSafe() bool {
+	return false
+}
+
+func (d *DeleteHeader) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (d *DeleteHeader) GetNamespace() types.NamespaceType {
+	return types.HTTP
+}
+
+func (d *DeleteHeader) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+func newHeaders() *storage.Storage {
+	return storage.NewStorage("http_headers", types.TAGGED, func(events.DisplayEvent) {})
+}
+
+func Test_CheckHost(t *testing.T) {
+	c := NewClient([]string{"example.com", "*.target.local", "10.0.0.0/24"}, 1024, time.Second, false)
+	cases := []struct {
+		url string
+		ok  bool
+	}{
+		{"http://example.com/", true},
+		{"https://EXAMPLE.com:8443/login", true},
+		{"http://www.example.com/", false},
+		{"http://api.target.local/", true},
+		{"http://target.local/", false},
+		{"http://eviltarget.local/", false},
+		{"http://10.0.0.5/", true},
+		{"http://10.0.1.5/", false},
+		{"ftp://example.com/", false},
+	}
+	for _, tc := range cases {
+		u, _ := url.Parse(tc.url)
+		if err := c.checkHost(u); (err == nil) != tc.ok {
+			t.Errorf("%s: expected allowed %v, got %v", tc.url, tc.ok, err)
+		}
+	}
+}
+
+func Test_RequestNotAllowed(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	r := NewRequest(NewClient([]string{"example.com"}, 1024, time.Second, false))
+	out := r.Run(newHeaders(), map[string]string{"method": "GET", "url": server.URL}, "")
+	if !strings.Contains(out, "not in the allowlist") || hit {
+		t.Fatalf("expected the request to be refused, got %q", out)
+	}
+}
+
+func Test_RedirectOutsideAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	r := NewRequest(NewClient([]string{"127.0.0.1"}, 1024, time.Second, false))
+	out := r.Run(newHeaders(), map[string]string{"method": "GET", "url": server.URL}, "")
+	if !strings.Contains(out, "not in the allowlist") {
+		t.Fatalf("expected the redirect to be refused, got %q", out)
+	}
+}
+
+func Test_HeadersAndCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+			}
+			r.ParseForm()
+			if r.Form.Get("user") != "admin" {
+				t.Errorf("unexpected body %v", r.Form)
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", MaxAge: -1})
+		default:
+			w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie")))
+		}
+	}))
+	defer server.Close()
+
+	headers := newHeaders()
+	r := NewRequest(NewClient(nil, 1024, time.Second, false))
+	r.Run(headers, map[string]string{"method": "POST", "url": server.URL + "/login"},
+		"\nContent-Type: application/x-www-form-urlencoded\n\nuser=admin&password=admin\n")
+
+	if entry, _ := headers.GetEntry("Cookie"); entry == nil || entry.Data != "session=abc; theme=dark" {
+		t.Fatalf("unexpected cookies %v", entry)
+	}
+
+	NewSetHeader().Run(headers, map[string]string{"name": "Authorization"}, "Bearer token")
+	out := r.Run(headers, map[string]string{"method": "GET", "url": server.URL + "/me"}, "")
+	if !strings.HasSuffix(out, "Bearer token|session=abc; theme=dark") {
+		t.Fatalf("headers were not sent, got %q", out)
+	}
+
+	r.Run(headers, map[string]string{"method": "GET", "url": server.URL + "/logout"}, "")
+	if entry, _ := headers.GetEntry("Cookie"); entry == nil || entry.Data != "theme=dark" {
+		t.Fatalf("expired cookie was not removed %v", entry)
+	}
+
+	NewDeleteHeader().Run(headers, nil, "Authorization")
+	out = r.Run(headers, map[string]string{"method": "GET", "url": server.URL + "/me"}, "")
+	if !strings.HasSuffix(out, "|theme=dark") {
+		t.Fatalf("deleted header was sent, got %q", out)
+	}
+}
+
+func Test_ResponseTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	r := NewRequest(NewClient(nil, 10, time.Second, false))
+	out := r.Run(newHeaders(), map[string]string{"method": "GET", "url": server.URL}, "")
+	if !strings.HasPrefix(out, "HTTP/1.1 200 OK\n") {
+		t.Fatalf("missing status line, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\n\naaaaaaaaaa\n... (body of more than 10 bytes truncated, 10 bytes shown)") {
+		t.Fatalf("unexpected truncation, got %q", out)
+	}
+}
+
+func Test_CompressedBodies(t *testing.T) {
+	compress := func(encoding string, data []byte) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "zlib":
+			w = zlib.NewWriter(&buf)
+		default:
+			w, _ = flate.NewWriter(&buf, flate.BestCompression)
+		}
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	// 64MiB once decompressed
+	bomb := compress("gzip", make([]byte, 64<<20))
+	bodies := map[string][]byte{
+		"/bomb": bomb,
+		"/zlib": compress("zlib", []byte("zlib body")),
+		"/raw":  compress("deflate", []byte("raw body")),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bomb" {
+			w.Header().Set("Content-Encoding", "gzip")
+		} else {
+			w.Header().Set("Content-Encoding", "deflate")
+		}
+		w.Write(bodies[r.URL.Path])
+	}))
+	defer server.Close()
+
+	r := NewRequest(NewClient(nil, 16, 5*time.Second, false))
+	out := r.Run(newHeaders(), map[string]string{"method": "GET", "url": server.URL + "/bomb"}, "")
+	if !strings.HasSuffix(out, "... (body of more than 16 bytes truncated, 16 bytes shown)") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	for path, want := range map[string]string{"/zlib": "zlib body", "/raw": "raw body"} {
+		if out := r.Run(newHeaders(), map[string]string{"method": "GET", "url": server.URL + path}, ""); !strings.HasSuffix(out, "\n\n"+want) {
+			t.Fatalf("%s: unexpected output %q", path, out)
+		}
+	}
+}
+
+func Test_ParsePayload(t *testing.T) {
+	headers, body := parsePayload(`{"user": "admin"}`)
+	if headers != nil || body != `{"user": "admin"}` {
+		t.Fatalf("json body parsed as headers: %v %q", headers, body)
+	}
+	headers, body = parsePayload("X-Token: 1")
+	if headers["X-Token"] != "1" || body != "" {
+		t.Fatalf("unexpected headers %v %q", headers, body)
+	}
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed request.prompt
+var requestPrompt string
+
+//go:embed ns.prompt
+var nsPrompt string
+
+// name: value, the name is an http token
+var headerLine = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+:")
+
+type Request struct {
+	client *Client
+}
+
+func NewRequest(client *Client) action.Action {
+	return &Request{
+		client: client,
+	}
+}
+
+func (r *Request) Name() string {
+	return "http_request"
+}
+
+func (r *Request) Description() string {
+	return requestPrompt
+}
+
+func (r *Request) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	method := strings.ToUpper(strings.TrimSpace(attributes["method"]))
+	if method == "" {
+		method = http.MethodGet
+	}
+	u, err := url.Parse(strings.TrimSpace(attributes["url"]))
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("invalid url '%s', an absolute http or https url is required", attributes["url"])
+	}
+
+	headers, body := parsePayload(payload)
+	req, err := http.NewRequest(method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return err.Error()
+	}
+	if body == "" {
+		req.Body = http.NoBody
+	}
+
+	// the headers of the storage, then the ones of the request
+	if storage != nil {
+		for name, entry := range storage.GetEntryList() {
+			setHeader(req, name, entry.Data)
+		}
+	}
+	for name, value := range headers {
+		setHeader(req, name, value)
+	}
+
+	res, output, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("request failed: %s", err.Error())
+	}
+
+	// cookies set by the response are sent with the next requests
+	if storage != nil {
+		saveCookies(storage, res.Cookies())
+	}
+	return output
+}
+
+func setHeader(req *http.Request, name, value string) {
+	if strings.EqualFold(name, "Host") {
+		req.Host = value
+		return
+	}
+	req.Header.Set(name, value)
+}
+
+// the lines before the first empty line are headers if they all look like one,
+// otherwise the whole payload is the body
+func parsePayload(payload string) (map[string]string, string) {
+	payload = strings.TrimSpace(strings.ReplaceAll(payload, "\r\n", "\n"))
+	if payload == "" {
+		return nil, ""
+	}
+
+	head, body, _ := strings.Cut(payload, "\n\n")
+	headers := map[string]string{}
+	for _, line := range strings.Split(head, "\n") {
+		if !headerLine.MatchString(line) {
+			return nil, payload
+		}
+		name, value, _ := strings.Cut(line, ":")
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, body
+}
+
+// merges the cookies into the Cookie header of the storage, expired cookies are removed
+func saveCookies(storage *storage.Storage, cookies []*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+
+	names := []string{}
+	values := map[string]string{}
+	if entry, found := storage.GetEntry("Cookie"); found {
+		for _, pair := range strings.Split(entry.Data, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if name != "" {
+				names = append(names, name)
+				values[name] = value
+			}
+		}
+	}
+
+	for _, cookie := range cookies {
+		if _, exists := values[cookie.Name]; !exists {
+			names = append(names, cookie.Name)
+		}
+		values[cookie.Name] = cookie.Value
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(values, cookie.Name)
+		}
+	}
+
+	pairs := []string{}
+	for _, name := range names {
+		if value, exists := values[name]; exists {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", name, value))
+			delete(values, name)
+		}
+	}
+	if len(pairs) == 0 {
+		storage.DelTagged("Cookie")
+		return
+	}
+	storage.AddTagged("Cookie", strings.Join(pairs, "; "))
+}
+
+func (r *Request) Timeout() *time.Duration {
+	return nil
+}
+
+func (r *Request) ExamplePayload() *string {
+	p := "Content-Type: application/x-www-form-urlencoded\n\nuser=admin&password=admin"
+	return &p
+}
+
+func (r *Request) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["method"] = "POST"
+	attr["url"] = "http://10.0.0.5/login"
+	return attr
+}
+
+func (r *Request) RequiredVariables() []*string {
+	return nil
+}
+
+func (r *Request) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (r *Request) ParallelSafe() bool {
+	return false
+}
+
+// a request can change the target, e.g. POST or DELETE
+func (r *Request) Effect() action.Effect {
+	return action.WRITE
+}
+
+func (r *Request) GetNamespace() types.NamespaceType {
+	return types.HTTP
+}
+
+func (r *Request) NamespaceDescription() string {
+	return nsPrompt
+}
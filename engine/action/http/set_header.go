@@ -0,0 +1,78 @@
+package http
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed set_header.prompt
+var setHeaderPrompt string
+
+type SetHeader struct {
+}
+
+func NewSetHeader() action.Action {
+	return &SetHeader{}
+}
+
+func (s *SetHeader) Name() string {
+	return "set_http_header"
+}
+
+func (s *SetHeader) Description() string {
+	return setHeaderPrompt
+}
+
+func (s *SetHeader) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	name := strings.TrimSpace(attributes["name"])
+	if !headerLine.MatchString(name + ":") {
+		return fmt.Sprintf("invalid header name '%s'", name)
+	}
+	storage.AddTagged(name, strings.TrimSpace(payload))
+	return fmt.Sprintf("header %s set", name)
+}
+
+func (s *SetHeader) Timeout() *time.Duration {
+	return nil
+}
+
+func (s *SetHeader) ExamplePayload() *string {
+	p := "Bearer eyJhbGciOiJIUzI1NiJ9"
+	return &p
+}
+
+func (s *SetHeader) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["name"] = "Authorization"
+	return attr
+}
+
+func (s *SetHeader) RequiredVariables() []*string {
+	return nil
+}
+
+func (s *SetHeader) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (s *SetHeader) ParallelSafe() bool {
+	return false
+}
+
+func (s *SetHeader) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (s *SetHeader) GetNamespace() types.NamespaceType {
+	return types.HTTP
+}
+
+func (s *SetHeader) NamespaceDescription() string {
+	return nsPrompt
+}
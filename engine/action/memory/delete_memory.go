@@ -2,6 +2,7 @@ package memory
 
 import (
 	_ "embed"
+	"fmt"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -32,8 +33,11 @@ func (m *DeleteMemory) Description() string {
 
 func (m *DeleteMemory) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
 	key := attributes["key"]
-	storage.AddTagged(key, payload)
-	return "memory saved"
+	if _, found := storage.GetEntry(key); !found {
+		return fmt.Sprintf("no memory with key %s", key)
+	}
+	storage.DelTagged(key)
+	return "memory deleted"
 }
 
 func (m *DeleteMemory) Timeout() *time.Duration {
@@ -41,8 +45,7 @@ func (m *DeleteMemory) Timeout() *time.Duration {
 }
 
 func (m *DeleteMemory) ExamplePayload() *string {
-	p := ""
-	return &p
+	return nil
 }
 
 func (m *DeleteMemory) ExampleAttributes() map[string]string {
@@ -59,6 +62,14 @@ func (m *DeleteMemory) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (m *DeleteMemory) ParallelSafe() bool {
+	return false
+}
+
+func (m *DeleteMemory) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (m *DeleteMemory) GetNamespace() types.NamespaceType {
 	return types.MEMORY
 }
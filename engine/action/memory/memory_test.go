@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+func newMemories() *storage.Storage {
+	s := storage.NewStorage(STORAGE, types.TAGGED, func(events.DisplayEvent) {})
+	s.AddTagged("ssh_credentials", "root:toor on 10.0.0.5")
+	s.AddTagged("open_ports", "22, 80 and 445")
+	s.AddTagged("web_server", "nginx 1.18 with an admin panel")
+	return s
+}
+
+func Test_RecallMemory(t *testing.T) {
+	s := newMemories()
+	recall := NewRecallMemory()
+
+	if out := recall.Run(s, nil, "open_ports"); out != "22, 80 and 445" {
+		t.Fatalf("unexpected memory %q", out)
+	}
+	if out := recall.Run(s, nil, "OPEN_PORTS"); out != "22, 80 and 445" {
+		t.Fatalf("expected a case insensitive match, got %q", out)
+	}
+	if out := recall.Run(s, nil, "open_port"); !strings.HasSuffix(out, "similar keys: open_ports") {
+		t.Fatalf("expected a suggestion, got %q", out)
+	}
+	if out := recall.Run(s, nil, "kerberos"); out != "no memory with key kerberos" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func Test_SearchMemories(t *testing.T) {
+	s := newMemories()
+	search := NewSearchMemories()
+
+	cases := []struct {
+		query string
+		want  string
+	}{
+		// substring of a key, then of a value
+		{"ssh", "ssh_credentials=root:toor on 10.0.0.5"},
+		{"ADMIN", "web_server=nginx 1.18 with an admin panel"},
+		// misspelled words
+		{"credentails", "ssh_credentials=root:toor on 10.0.0.5"},
+		{"ngnix", "web_server=nginx 1.18 with an admin panel"},
+		{"kerberos", "no memory matches 'kerberos'"},
+	}
+	for _, tc := range cases {
+		if out := search.Run(s, nil, tc.query); out != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.query, tc.want, out)
+		}
+	}
+}
+
+func Test_DeleteMemory(t *testing.T) {
+	s := newMemories()
+	del := NewDeleteMemory()
+
+	if out := del.Run(s, map[string]string{"key": "open_ports"}, ""); out != "memory deleted" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, found := s.GetEntry("open_ports"); found {
+		t.Fatal("memory was not deleted")
+	}
+	if out := del.Run(s, map[string]string{"key": "open_ports"}, ""); out != "no memory with key open_ports" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
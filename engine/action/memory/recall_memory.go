@@ -0,0 +1,87 @@
+package memory
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed recall.prompt
+var recallPrompt string
+
+type RecallMemory struct {
+}
+
+func NewRecallMemory() action.Action {
+	return &RecallMemory{}
+}
+
+func (m *RecallMemory) Name() string {
+	return "recall_memory"
+}
+
+func (m *RecallMemory) Description() string {
+	return recallPrompt
+}
+
+func (m *RecallMemory) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	key := strings.TrimSpace(payload)
+	if entry, found := storage.GetEntry(key); found {
+		return entry.Data
+	}
+
+	// the key is likely misspelled, the closest keys are suggested
+	similar := []string{}
+	for _, match := range searchMemories(storage, key, 3) {
+		if strings.EqualFold(match.key, key) {
+			return match.value
+		}
+		similar = append(similar, match.key)
+	}
+	if len(similar) == 0 {
+		return fmt.Sprintf("no memory with key %s", key)
+	}
+	return fmt.Sprintf("no memory with key %s, similar keys: %s", key, strings.Join(similar, ", "))
+}
+
+func (m *RecallMemory) Timeout() *time.Duration {
+	return nil
+}
+
+func (m *RecallMemory) ExamplePayload() *string {
+	p := "note"
+	return &p
+}
+
+func (m *RecallMemory) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (m *RecallMemory) RequiredVariables() []*string {
+	return nil
+}
+
+func (m *RecallMemory) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (m *RecallMemory) ParallelSafe() bool {
+	return true
+}
+
+func (m *RecallMemory) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (m *RecallMemory) GetNamespace() types.NamespaceType {
+	return types.MEMORY
+}
+
+func (m *RecallMemory) NamespaceDescription() string {
+	return nsPrompt
+}
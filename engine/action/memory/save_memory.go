@@ -60,6 +60,14 @@ func (m *SaveMemory) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (m *SaveMemory) ParallelSafe() bool {
+	return false
+}
+
+func (m *SaveMemory) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (m *SaveMemory) NamespaceDescription() string {
 	return nsPrompt
 }
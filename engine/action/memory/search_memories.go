@@ -0,0 +1,177 @@
+package memory
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed search.prompt
+var searchPrompt string
+
+// name of the storage of the memories
+const STORAGE = "memories"
+
+// memories returned by a search
+const maxMatches = 10
+
+// words this similar to the query match, from 0 to 1
+const minSimilarity = 0.7
+
+type SearchMemories struct {
+}
+
+func NewSearchMemories() action.Action {
+	return &SearchMemories{}
+}
+
+func (m *SearchMemories) Name() string {
+	return "search_memories"
+}
+
+func (m *SearchMemories) Description() string {
+	return searchPrompt
+}
+
+func (m *SearchMemories) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	query := strings.TrimSpace(payload)
+	if query == "" {
+		return "a query is required"
+	}
+
+	matches := searchMemories(storage, query, maxMatches)
+	if len(matches) == 0 {
+		return fmt.Sprintf("no memory matches '%s'", query)
+	}
+	var sb strings.Builder
+	for _, match := range matches {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", match.key, match.value))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+type match struct {
+	key   string
+	value string
+	score float64
+}
+
+// substrings of the keys rank first, then substrings of the values,
+// then keys and words similar to the query
+func searchMemories(storage *storage.Storage, query string, limit int) []*match {
+	query = strings.ToLower(query)
+	matches := []*match{}
+	for key, entry := range storage.GetEntryList() {
+		lowerKey := strings.ToLower(key)
+		score := 0.0
+		switch {
+		case strings.Contains(lowerKey, query):
+			score = 2
+		case strings.Contains(strings.ToLower(entry.Data), query):
+			score = 1.5
+		default:
+			score = similarity(query, lowerKey)
+			words := strings.FieldsFunc(lowerKey+" "+strings.ToLower(entry.Data), isSeparator)
+			for _, word := range words {
+				score = max(score, similarity(query, word))
+			}
+			if score < minSimilarity {
+				continue
+			}
+		}
+		matches = append(matches, &match{key: key, value: entry.Data, score: score})
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return matches[a].key < matches[b].key
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// 1 minus the edit distance relative to the longest word, swapped letters count as one edit
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(distance(ra, rb))/float64(longest)
+}
+
+func distance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func (m *SearchMemories) Timeout() *time.Duration {
+	return nil
+}
+
+func (m *SearchMemories) ExamplePayload() *string {
+	p := "credentials"
+	return &p
+}
+
+func (m *SearchMemories) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (m *SearchMemories) RequiredVariables() []*string {
+	return nil
+}
+
+func (m *SearchMemories) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (m *SearchMemories) ParallelSafe() bool {
+	return true
+}
+
+func (m *SearchMemories) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (m *SearchMemories) GetNamespace() types.NamespaceType {
+	return types.MEMORY
+}
+
+func (m *SearchMemories) NamespaceDescription() string {
+	return nsPrompt
+}
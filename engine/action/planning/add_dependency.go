@@ -0,0 +1,77 @@
+package planning
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed depend.prompt
+var dependPrompt string
+
+type AddDependency struct {
+}
+
+func NewAddDependency() action.Action {
+	return &AddDependency{}
+}
+
+func (a *AddDependency) Name() string {
+	return "add_step_dependency"
+}
+
+func (a *AddDependency) Description() string {
+	return dependPrompt
+}
+
+func (a *AddDependency) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	pos, after := strings.TrimSpace(attributes["position"]), strings.TrimSpace(attributes["after"])
+	if err := storage.AddDependency(pos, after); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s waits until step %s is done", pos, after)
+}
+
+func (a *AddDependency) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *AddDependency) ExamplePayload() *string {
+	return nil
+}
+
+func (a *AddDependency) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["position"] = "3"
+	attr["after"] = "2"
+	return attr
+}
+
+func (a *AddDependency) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *AddDependency) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *AddDependency) ParallelSafe() bool {
+	return false
+}
+
+func (a *AddDependency) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *AddDependency) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *AddDependency) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -2,6 +2,8 @@ package planning
 
 import (
 	_ "embed"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -31,8 +33,12 @@ func (a *AddStep) Description() string {
 }
 
 func (a *AddStep) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	storage.AddCompletion(payload)
-	return "step added to the plan"
+	// a sub step of the parent if given
+	path, err := storage.AddStep(strings.TrimSpace(attributes["parent"]), strings.TrimSpace(payload))
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s added to the plan", path)
 }
 
 func (a *AddStep) Timeout() *time.Duration {
@@ -56,6 +62,14 @@ func (a *AddStep) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (a *AddStep) ParallelSafe() bool {
+	return false
+}
+
+func (a *AddStep) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (a *AddStep) GetNamespace() types.NamespaceType {
 	return types.PLANNING
 }
@@ -63,3 +77,16 @@ func (a *AddStep) GetNamespace() types.NamespaceType {
 func (a *AddStep) NamespaceDescription() string {
 	return nsPrompt
 }
+
+// sets the status of the step at the position
+func setStatus(plan *storage.Storage, position string, value string) string {
+	status, err := storage.ParseStepStatus(value)
+	if err != nil {
+		return err.Error()
+	}
+	position = strings.TrimSpace(position)
+	if err := plan.SetStepStatus(position, status); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s is %s", position, status)
+}
@@ -28,7 +28,8 @@ func (a *Clear) Description() string {
 }
 
 func (a *Clear) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	return "plan clear"
+	storage.Clear()
+	return "plan cleared"
 }
 
 func (a *Clear) Timeout() *time.Duration {
@@ -36,8 +37,7 @@ func (a *Clear) Timeout() *time.Duration {
 }
 
 func (a *Clear) ExamplePayload() *string {
-	p := "complete the task"
-	return &p
+	return nil
 }
 
 func (a *Clear) ExampleAttributes() map[string]string {
@@ -52,6 +52,14 @@ func (a *Clear) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (a *Clear) ParallelSafe() bool {
+	return false
+}
+
+func (a *Clear) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (a *Clear) GetNamespace() types.NamespaceType {
 	return types.PLANNING
 }
@@ -2,7 +2,8 @@ package planning
 
 import (
 	_ "embed"
-	"strconv"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -29,9 +30,11 @@ func (a *DeleteStep) Description() string {
 }
 
 func (a *DeleteStep) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	pos, _ := strconv.Atoi(payload)
-	storage.DelCompletion(pos)
-	return "step removed from the plan"
+	pos := strings.TrimSpace(payload)
+	if err := storage.DelStep(pos); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s removed from the plan with its sub steps, the next steps moved up", pos)
 }
 
 func (a *DeleteStep) Timeout() *time.Duration {
@@ -55,6 +58,14 @@ func (a *DeleteStep) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (a *DeleteStep) ParallelSafe() bool {
+	return false
+}
+
+func (a *DeleteStep) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (a *DeleteStep) GetNamespace() types.NamespaceType {
 	return types.PLANNING
 }
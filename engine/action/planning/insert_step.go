@@ -0,0 +1,77 @@
+package planning
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed insert.prompt
+var insertPrompt string
+
+type InsertStep struct {
+}
+
+func NewInsertStep() action.Action {
+	return &InsertStep{}
+}
+
+func (a *InsertStep) Name() string {
+	return "insert_plan_step"
+}
+
+func (a *InsertStep) Description() string {
+	return insertPrompt
+}
+
+func (a *InsertStep) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	pos := strings.TrimSpace(attributes["position"])
+	if err := storage.InsertStep(pos, strings.TrimSpace(payload)); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step inserted at position %s", pos)
+}
+
+func (a *InsertStep) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *InsertStep) ExamplePayload() *string {
+	p := "check the login page for default credentials"
+	return &p
+}
+
+func (a *InsertStep) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["position"] = "2"
+	return attr
+}
+
+func (a *InsertStep) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *InsertStep) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *InsertStep) ParallelSafe() bool {
+	return false
+}
+
+func (a *InsertStep) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *InsertStep) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *InsertStep) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,77 @@
+package planning
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed move.prompt
+var movePrompt string
+
+type MoveStep struct {
+}
+
+func NewMoveStep() action.Action {
+	return &MoveStep{}
+}
+
+func (a *MoveStep) Name() string {
+	return "move_plan_step"
+}
+
+func (a *MoveStep) Description() string {
+	return movePrompt
+}
+
+func (a *MoveStep) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	from, to := strings.TrimSpace(attributes["from"]), strings.TrimSpace(attributes["to"])
+	if err := storage.MoveStep(from, to); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s moved to position %s", from, to)
+}
+
+func (a *MoveStep) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *MoveStep) ExamplePayload() *string {
+	return nil
+}
+
+func (a *MoveStep) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["from"] = "3"
+	attr["to"] = "1"
+	return attr
+}
+
+func (a *MoveStep) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *MoveStep) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *MoveStep) ParallelSafe() bool {
+	return false
+}
+
+func (a *MoveStep) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *MoveStep) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *MoveStep) NamespaceDescription() string {
+	return nsPrompt
+}
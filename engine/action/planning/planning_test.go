@@ -0,0 +1,79 @@
+package planning
+
+import (
+	"testing"
+
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+func Test_PlanActions(t *testing.T) {
+	plan := storage.NewStorage("plan", types.COMPLETION, func(events.DisplayEvent) {})
+	add := NewAddStep()
+	add.Run(plan, nil, "scan the host")
+	add.Run(plan, nil, "report")
+
+	cases := []struct {
+		run  func() string
+		want string
+	}{
+		{func() string { return NewInsertStep().Run(plan, map[string]string{"position": "2"}, "exploit") }, "step inserted at position 2"},
+		{func() string { return NewMoveStep().Run(plan, map[string]string{"from": "3", "to": "1"}, "") }, "step 3 moved to position 1"},
+		{func() string {
+			return NewRenameStep().Run(plan, map[string]string{"position": "1"}, "write the report")
+		}, "step 1 renamed"},
+		{func() string { return NewSetComplete().Run(plan, nil, "2") }, "step 2 is done"},
+		{func() string { return NewSetInComplete().Run(plan, nil, "2") }, "step 2 is pending"},
+		{func() string { return NewDeleteStep().Run(plan, nil, "3") }, "step 3 removed from the plan with its sub steps, the next steps moved up"},
+		{func() string { return NewSetComplete().Run(plan, nil, "3") }, "no step at position 3, the plan has 2 steps"},
+		{func() string { return NewDeleteStep().Run(plan, nil, "first") }, "invalid position 'first', expected the number of a plan step such as 2 or 2.1"},
+	}
+	for _, tc := range cases {
+		if got := tc.run(); got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+
+	steps := plan.GetSteps()
+	if len(steps) != 2 || steps[0].Data != "write the report" || steps[1].Data != "scan the host" {
+		t.Fatalf("unexpected plan %v", steps)
+	}
+
+	cases = []struct {
+		run  func() string
+		want string
+	}{
+		{func() string { return NewAddStep().Run(plan, map[string]string{"parent": "2"}, "nmap") }, "step 2.1 added to the plan"},
+		{func() string { return NewAddStep().Run(plan, map[string]string{"parent": "2"}, "masscan") }, "step 2.2 added to the plan"},
+		{func() string {
+			return NewSetStatus().Run(plan, map[string]string{"position": "2.1"}, "failed")
+		}, "step 2.1 is failed"},
+		{func() string { return NewSetStatus().Run(plan, map[string]string{"position": "2"}, "done") }, "step 2 takes its status from its sub steps"},
+		{func() string { return NewSetStatus().Run(plan, map[string]string{"position": "2.2"}, "later") }, "invalid status 'later', expected pending, in-progress, blocked, done or failed"},
+		{func() string {
+			return NewAddDependency().Run(plan, map[string]string{"position": "1", "after": "2.2"}, "")
+		}, "step 1 waits until step 2.2 is done"},
+		{func() string {
+			return NewAddDependency().Run(plan, map[string]string{"position": "2.2", "after": "1"}, "")
+		}, "step 1 already waits for step 2.2"},
+		{func() string {
+			return NewAddDependency().Run(plan, map[string]string{"position": "2.1", "after": "2"}, "")
+		}, "step 2.1 can not wait for step 2, one is part of the other"},
+		{func() string {
+			return NewRemoveDependency().Run(plan, map[string]string{"position": "1", "after": "2.2"}, "")
+		}, "step 1 no longer waits for step 2.2"},
+		{func() string {
+			return NewRemoveDependency().Run(plan, map[string]string{"position": "1", "after": "2.2"}, "")
+		}, "step 1 does not wait for step 2.2"},
+	}
+	for _, tc := range cases {
+		if got := tc.run(); got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+
+	if got := NewClear().Run(plan, nil, ""); got != "plan cleared" || !plan.IsEmpty() {
+		t.Fatalf("plan was not cleared: %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package planning
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed undepend.prompt
+var undependPrompt string
+
+type RemoveDependency struct {
+}
+
+func NewRemoveDependency() action.Action {
+	return &RemoveDependency{}
+}
+
+func (a *RemoveDependency) Name() string {
+	return "remove_step_dependency"
+}
+
+func (a *RemoveDependency) Description() string {
+	return undependPrompt
+}
+
+func (a *RemoveDependency) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	pos, after := strings.TrimSpace(attributes["position"]), strings.TrimSpace(attributes["after"])
+	if err := storage.RemoveDependency(pos, after); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s no longer waits for step %s", pos, after)
+}
+
+func (a *RemoveDependency) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *RemoveDependency) ExamplePayload() *string {
+	return nil
+}
+
+func (a *RemoveDependency) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["position"] = "3"
+	attr["after"] = "2"
+	return attr
+}
+
+func (a *RemoveDependency) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *RemoveDependency) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *RemoveDependency) ParallelSafe() bool {
+	return false
+}
+
+func (a *RemoveDependency) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *RemoveDependency) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *RemoveDependency) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,77 @@
+package planning
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed rename.prompt
+var renamePrompt string
+
+type RenameStep struct {
+}
+
+func NewRenameStep() action.Action {
+	return &RenameStep{}
+}
+
+func (a *RenameStep) Name() string {
+	return "rename_plan_step"
+}
+
+func (a *RenameStep) Description() string {
+	return renamePrompt
+}
+
+func (a *RenameStep) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	pos := strings.TrimSpace(attributes["position"])
+	if err := storage.RenameStep(pos, strings.TrimSpace(payload)); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("step %s renamed", pos)
+}
+
+func (a *RenameStep) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *RenameStep) ExamplePayload() *string {
+	p := "scan every tcp port of the host"
+	return &p
+}
+
+func (a *RenameStep) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["position"] = "1"
+	return attr
+}
+
+func (a *RenameStep) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *RenameStep) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *RenameStep) ParallelSafe() bool {
+	return false
+}
+
+func (a *RenameStep) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *RenameStep) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *RenameStep) NamespaceDescription() string {
+	return nsPrompt
+}
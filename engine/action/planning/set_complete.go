@@ -2,8 +2,6 @@ package planning
 
 import (
 	_ "embed"
-	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -30,11 +28,7 @@ func (s *SetComplete) Description() string {
 }
 
 func (s *SetComplete) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	pos, _ := strconv.Atoi(payload)
-	if storage.SetComplete(pos) {
-		return fmt.Sprintf("step %d marked as completed", pos)
-	}
-	return fmt.Sprintf("no plan step at position %d", pos)
+	return setStatus(storage, payload, "done")
 }
 
 func (s *SetComplete) Timeout() *time.Duration {
@@ -58,6 +52,14 @@ func (s *SetComplete) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (s *SetComplete) ParallelSafe() bool {
+	return false
+}
+
+func (s *SetComplete) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (s *SetComplete) GetNamespace() types.NamespaceType {
 	return types.PLANNING
 }
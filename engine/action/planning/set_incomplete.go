@@ -2,8 +2,6 @@ package planning
 
 import (
 	_ "embed"
-	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -30,11 +28,7 @@ func (s *SetInComplete) Description() string {
 }
 
 func (s *SetInComplete) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	pos, _ := strconv.Atoi(payload)
-	if storage.SetInComplete(pos) {
-		return fmt.Sprintf("step %d marked as completed", pos)
-	}
-	return fmt.Sprintf("no plan step at position %d", pos)
+	return setStatus(storage, payload, "pending")
 }
 
 func (s *SetInComplete) Timeout() *time.Duration {
@@ -58,6 +52,14 @@ func (s *SetInComplete) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (s *SetInComplete) ParallelSafe() bool {
+	return false
+}
+
+func (s *SetInComplete) Effect() action.Effect {
+	return action.INTERNAL
+}
+
 func (s *SetInComplete) GetNamespace() types.NamespaceType {
 	return types.PLANNING
 }
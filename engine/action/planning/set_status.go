@@ -0,0 +1,71 @@
+package planning
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed set-status.prompt
+var setStatusPrompt string
+
+type SetStatus struct {
+}
+
+func NewSetStatus() action.Action {
+	return &SetStatus{}
+}
+
+func (a *SetStatus) Name() string {
+	return "set_step_status"
+}
+
+func (a *SetStatus) Description() string {
+	return setStatusPrompt
+}
+
+func (a *SetStatus) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	return setStatus(storage, attributes["position"], payload)
+}
+
+func (a *SetStatus) Timeout() *time.Duration {
+	return nil
+}
+
+func (a *SetStatus) ExamplePayload() *string {
+	p := "blocked"
+	return &p
+}
+
+func (a *SetStatus) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["position"] = "2.1"
+	return attr
+}
+
+func (a *SetStatus) RequiredVariables() []*string {
+	return nil
+}
+
+func (a *SetStatus) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (a *SetStatus) ParallelSafe() bool {
+	return false
+}
+
+func (a *SetStatus) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (a *SetStatus) GetNamespace() types.NamespaceType {
+	return types.PLANNING
+}
+
+func (a *SetStatus) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// bm25 parameters, the usual defaults
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// term statistics of the chunks, used when no embedder is configured
+type bm25 struct {
+	terms   []map[string]int
+	lengths []int
+	average float64
+	// chunks containing each term
+	frequency map[string]int
+}
+
+func newBM25(chunks []*Chunk) *bm25 {
+	m := &bm25{
+		terms:     make([]map[string]int, len(chunks)),
+		lengths:   make([]int, len(chunks)),
+		frequency: map[string]int{},
+	}
+	total := 0
+	for i, chunk := range chunks {
+		tokens := tokenize(chunk.Text)
+		counts := map[string]int{}
+		for _, token := range tokens {
+			counts[token]++
+		}
+		for token := range counts {
+			m.frequency[token]++
+		}
+		m.terms[i] = counts
+		m.lengths[i] = len(tokens)
+		total += len(tokens)
+	}
+	if len(chunks) > 0 {
+		m.average = float64(total) / float64(len(chunks))
+	}
+	return m
+}
+
+func (m *bm25) scores(query string) []float64 {
+	scores := make([]float64, len(m.terms))
+	n := float64(len(m.terms))
+	seen := map[string]bool{}
+	for _, token := range tokenize(query) {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		df := float64(m.frequency[token])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for i, counts := range m.terms {
+			tf := float64(counts[token])
+			if tf == 0 {
+				continue
+			}
+			norm := 1 - b + b*float64(m.lengths[i])/m.average
+			scores[i] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+	return scores
+}
+
+// lower case words and numbers
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,259 @@
+package rag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// larger files are not indexed
+const maxFileSize = 1024 * 1024
+
+// chunks sent in a single embedding request
+const embedBatch = 64
+
+// embeds texts into vectors, implemented by the llm clients
+type Embedder interface {
+	Name() string
+	Embed(texts []string) ([][]float32, error)
+}
+
+// a passage of a document
+type Chunk struct {
+	Source string
+	From   int
+	To     int
+	Text   string
+}
+
+type Result struct {
+	Chunk *Chunk
+	Score float64
+}
+
+// documents of a folder, indexed at the first search
+type Index struct {
+	root      string
+	chunkSize int
+	embedder  Embedder
+	cache     string
+
+	once    sync.Once
+	err     error
+	chunks  []*Chunk
+	vectors [][]float32
+	bm25    *bm25
+}
+
+// the chunks are ranked by the embedder, or by bm25 if nil
+func NewIndex(root string, chunkSize int, embedder Embedder, cache string) *Index {
+	return &Index{
+		root:      root,
+		chunkSize: chunkSize,
+		embedder:  embedder,
+		cache:     cache,
+	}
+}
+
+func (i *Index) Search(query string, k int) ([]*Result, error) {
+	i.once.Do(i.build)
+	if i.err != nil {
+		return nil, i.err
+	}
+
+	var scores []float64
+	if i.vectors != nil {
+		vectors, err := i.embedder.Embed([]string{query})
+		if err == nil && len(vectors) == 1 {
+			scores = make([]float64, len(i.vectors))
+			for n, vector := range i.vectors {
+				scores[n] = cosine(vectors[0], vector)
+			}
+		} else {
+			log.Printf("rag embedding of the query failed, using bm25: %v", err)
+		}
+	}
+	if scores == nil {
+		scores = i.bm25.scores(query)
+	}
+
+	results := []*Result{}
+	for n, score := range scores {
+		if score > 0 {
+			results = append(results, &Result{Chunk: i.chunks[n], Score: score})
+		}
+	}
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func (i *Index) build() {
+	if i.root == "" {
+		i.err = errors.New("no document folder is configured for the rag namespace")
+		return
+	}
+
+	err := filepath.WalkDir(i.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != i.root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// binary files are not documents
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return nil
+		}
+		source, _ := filepath.Rel(i.root, path)
+		i.chunks = append(i.chunks, split(filepath.ToSlash(source), string(data), i.chunkSize)...)
+		return nil
+	})
+	if err != nil {
+		i.err = fmt.Errorf("indexing %s: %s", i.root, err.Error())
+		return
+	}
+	log.Printf("rag indexed %d chunks of %s", len(i.chunks), i.root)
+
+	i.bm25 = newBM25(i.chunks)
+	if i.embedder != nil {
+		if err := i.embed(); err != nil {
+			log.Printf("rag embedding failed, using bm25: %s", err.Error())
+			i.vectors = nil
+		}
+	}
+}
+
+// splits the lines of a document into chunks of about size characters,
+// preferably at empty lines
+func split(source, text string, size int) []*Chunk {
+	chunks := []*Chunk{}
+	var sb strings.Builder
+	from := 1
+	flush := func(to int) {
+		if strings.TrimSpace(sb.String()) != "" {
+			chunks = append(chunks, &Chunk{Source: source, From: from, To: to, Text: strings.TrimSpace(sb.String())})
+		}
+		sb.Reset()
+		from = to + 1
+	}
+
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	for n, line := range lines {
+		if sb.Len() > 0 && sb.Len()+len(line) > size {
+			flush(n)
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if strings.TrimSpace(line) == "" && sb.Len() > size/2 {
+			flush(n + 1)
+		}
+	}
+	flush(len(lines))
+	return chunks
+}
+
+// vectors of the chunks, the cache keeps them between runs
+func (i *Index) embed() error {
+	cached := map[string][]float32{}
+	if i.cache != "" {
+		if data, err := os.ReadFile(i.cache); err == nil {
+			if err := json.Unmarshal(data, &cached); err != nil {
+				log.Printf("rag cache %s is ignored: %s", i.cache, err.Error())
+			}
+		}
+	}
+
+	keys := make([]string, len(i.chunks))
+	missing := []int{}
+	for n, chunk := range i.chunks {
+		sum := sha256.Sum256([]byte(i.embedder.Name() + "\x00" + chunk.Text))
+		keys[n] = hex.EncodeToString(sum[:])
+		if _, found := cached[keys[n]]; !found {
+			missing = append(missing, n)
+		}
+	}
+
+	for start := 0; start < len(missing); start += embedBatch {
+		batch := missing[start:min(start+embedBatch, len(missing))]
+		texts := make([]string, len(batch))
+		for n, index := range batch {
+			texts[n] = i.chunks[index].Text
+		}
+		vectors, err := i.embedder.Embed(texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vectors))
+		}
+		for n, index := range batch {
+			cached[keys[index]] = vectors[n]
+		}
+	}
+
+	i.vectors = make([][]float32, len(i.chunks))
+	used := map[string][]float32{}
+	for n, key := range keys {
+		i.vectors[n] = cached[key]
+		used[key] = cached[key]
+	}
+
+	// only the vectors of the current chunks are kept
+	if i.cache != "" && len(missing) > 0 {
+		data, err := json.Marshal(used)
+		if err == nil {
+			err = os.WriteFile(i.cache, data, 0644)
+		}
+		if err != nil {
+			log.Printf("rag cache %s not saved: %s", i.cache, err.Error())
+		}
+	}
+	return nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for n := range a {
+		dot += float64(a[n]) * float64(b[n])
+		na += float64(a[n]) * float64(a[n])
+		nb += float64(b[n]) * float64(b[n])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
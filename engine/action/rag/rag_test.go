@@ -0,0 +1,115 @@
+package rag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDocs(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func Test_Split(t *testing.T) {
+	text := "# ssh\nuse hydra\n\n# smb\nuse smbclient\nlist shares\n"
+	chunks := split("playbook.md", text, 32)
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[0].From != 1 || chunks[0].To != 3 || chunks[0].Text != "# ssh\nuse hydra" {
+		t.Fatalf("unexpected first chunk %+v", chunks[0])
+	}
+	if chunks[1].From != 4 || chunks[1].To != 6 {
+		t.Fatalf("unexpected second chunk %+v", chunks[1])
+	}
+}
+
+func Test_SearchBM25(t *testing.T) {
+	root := writeDocs(t, map[string]string{
+		"ssh.md":          "SSH brute force: try the default credentials with hydra.",
+		"web/sqli.md":     "SQL injection: test every parameter with sqlmap.",
+		".git/config":     "ssh ssh ssh",
+		"tools/scan.bin":  "ssh\x00\x01",
+		"notes/empty.txt": "",
+	})
+
+	s := NewSearch(NewIndex(root, 1000, nil, ""), 5)
+	out := s.Run(nil, nil, "ssh credentials")
+	if !strings.HasPrefix(out, "1. ssh.md:1-1 (score ") || strings.Contains(out, "2.") {
+		t.Fatalf("unexpected results %q", out)
+	}
+	if out := s.Run(nil, nil, "kerberos"); !strings.HasPrefix(out, "no document matches") {
+		t.Fatalf("unexpected results %q", out)
+	}
+}
+
+type fakeEmbedder struct {
+	calls int
+	fail  bool
+}
+
+func (f *fakeEmbedder) Name() string {
+	return "fake"
+}
+
+// one dimension per topic
+func (f *fakeEmbedder) Embed(texts []string) ([][]float32, error) {
+	f.calls++
+	if f.fail {
+		return nil, errors.New("offline")
+	}
+	vectors := [][]float32{}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		vectors = append(vectors, []float32{
+			float32(strings.Count(text, "ssh")),
+			float32(strings.Count(text, "sql")),
+		})
+	}
+	return vectors, nil
+}
+
+func Test_SearchEmbedder(t *testing.T) {
+	root := writeDocs(t, map[string]string{
+		"ssh.md":  "SSH brute force with hydra.",
+		"sqli.md": "SQL injection with sqlmap.",
+	})
+	cache := filepath.Join(t.TempDir(), "cache.json")
+
+	embedder := &fakeEmbedder{}
+	results, err := NewIndex(root, 1000, embedder, cache).Search("sql", 1)
+	if err != nil || len(results) != 1 || results[0].Chunk.Source != "sqli.md" {
+		t.Fatalf("unexpected results %v %v", results, err)
+	}
+
+	// the chunks are embedded from the cache, only the query is embedded
+	embedder = &fakeEmbedder{}
+	if _, err := NewIndex(root, 1000, embedder, cache).Search("ssh", 1); err != nil || embedder.calls != 1 {
+		t.Fatalf("expected the cache to be used, got %d calls %v", embedder.calls, err)
+	}
+
+	// bm25 is used when the embedder fails
+	results, err = NewIndex(root, 1000, &fakeEmbedder{fail: true}, "").Search("hydra", 1)
+	if err != nil || len(results) != 1 || results[0].Chunk.Source != "ssh.md" {
+		t.Fatalf("unexpected fallback results %v %v", results, err)
+	}
+}
+
+func Test_SearchWithoutFolder(t *testing.T) {
+	out := NewSearch(NewIndex("", 1000, nil, ""), 5).Run(nil, nil, "ssh")
+	if !strings.Contains(out, "no document folder") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
@@ -0,0 +1,107 @@
+package rag
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed search.prompt
+var searchPrompt string
+
+//go:embed ns.prompt
+var nsPrompt string
+
+type Search struct {
+	index *Index
+	topK  int
+}
+
+func NewSearch(index *Index, topK int) action.Action {
+	return &Search{
+		index: index,
+		topK:  topK,
+	}
+}
+
+func (s *Search) Name() string {
+	return "search"
+}
+
+func (s *Search) Description() string {
+	return searchPrompt
+}
+
+func (s *Search) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	query := strings.TrimSpace(payload)
+	if query == "" {
+		return "a query is required"
+	}
+	// the number of results can be given with limit
+	k := s.topK
+	if limit, err := strconv.Atoi(attributes["limit"]); err == nil && limit > 0 {
+		k = limit
+	}
+
+	results, err := s.index.Search(query, k)
+	if err != nil {
+		return err.Error()
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("no document matches '%s'", query)
+	}
+
+	var sb strings.Builder
+	for n, result := range results {
+		if n > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s:%d-%d (score %.3f)\n%s",
+			n+1, result.Chunk.Source, result.Chunk.From, result.Chunk.To, result.Score, result.Chunk.Text))
+	}
+	return sb.String()
+}
+
+func (s *Search) Timeout() *time.Duration {
+	return nil
+}
+
+func (s *Search) ExamplePayload() *string {
+	p := "ssh brute force with default credentials"
+	return &p
+}
+
+func (s *Search) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (s *Search) RequiredVariables() []*string {
+	return nil
+}
+
+func (s *Search) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (s *Search) ParallelSafe() bool {
+	return true
+}
+
+// the documents are local knowledge, they can be consulted while planning
+func (s *Search) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (s *Search) GetNamespace() types.NamespaceType {
+	return types.RAG
+}
+
+func (s *Search) NamespaceDescription() string {
+	return nsPrompt
+}
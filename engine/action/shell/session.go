@@ -0,0 +1,378 @@
+package shell
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const DEFAULT_SESSION = "default"
+
+// variables changed by the shell itself, not shown to the model
+var ignoredVariables = map[string]bool{"PWD": true, "OLDPWD": true, "SHLVL": true, "_": true}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// output of a command run in a session
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// directory and environment of the session after the command
+	Dir string
+	Env map[string]string
+}
+
+// named sessions of a run, each one is started by its first command
+type Sessions struct {
+	mu       sync.Mutex
+	program  string
+	timeout  time.Duration
+	sessions map[string]*Session
+}
+
+// timeout is the time a command may run before its session is restarted, 0 is the no limit
+func NewSessions(program string, timeout time.Duration) *Sessions {
+	return &Sessions{
+		program:  program,
+		timeout:  timeout,
+		sessions: map[string]*Session{},
+	}
+}
+
+func (s *Sessions) Get(name string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, found := s.sessions[name]
+	if !found {
+		session = &Session{
+			name:    name,
+			program: s.program,
+			timeout: s.timeout,
+		}
+		s.sessions[name] = session
+	}
+	return session
+}
+
+// stops every session
+func (s *Sessions) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		session.Close()
+	}
+}
+
+// a long lived shell, its commands share the directory and the exported variables.
+// each command is sourced from a file and followed by marker lines carrying its exit code,
+// the directory and the environment, the output before the markers is the output of the command
+type Session struct {
+	mu      sync.Mutex
+	name    string
+	program string
+	timeout time.Duration
+	marker  string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *bufio.Reader
+	// environment at the start of the session, the changes are shown to the model
+	initial map[string]string
+	last    *Result
+
+	// the process group of the shell, Close kills it without waiting for the running command
+	procMu sync.Mutex
+	pgid   int
+	closed bool
+}
+
+func (s *Session) Name() string {
+	return s.name
+}
+
+// runs the command, the session is started if needed and restarted after an error
+func (s *Session) Run(command string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+
+	// an unterminated quote or block would read the markers as part of the command
+	if out, err := exec.Command(s.program, "-n", "-c", command).CombinedOutput(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		return &Result{
+			Stderr:   string(out),
+			ExitCode: exitErr.ExitCode(),
+			Dir:      s.last.Dir,
+			Env:      s.last.Env,
+		}, nil
+	}
+
+	res, err := s.exec(command)
+	if err != nil {
+		s.stop()
+		return nil, err
+	}
+	return res, nil
+}
+
+// the directory and the variables changed since the start of the session
+func (s *Session) Describe(res *Result) string {
+	changes := []string{}
+	for key, value := range res.Env {
+		if initial, found := s.initial[key]; (!found || initial != value) && !ignoredVariables[key] {
+			changes = append(changes, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	for key := range s.initial {
+		if _, found := res.Env[key]; !found && !ignoredVariables[key] {
+			changes = append(changes, fmt.Sprintf("unset %s", key))
+		}
+	}
+	sort.Strings(changes)
+
+	description := Location(s.name, res.Dir)
+	if len(changes) > 0 {
+		description += ", environment changed: " + strings.Join(changes, ", ")
+	}
+	return description
+}
+
+// where the session is, shown in the shell storage
+func Location(name, dir string) string {
+	return fmt.Sprintf("%s session in %s", name, dir)
+}
+
+// kills the shell and the commands it started, a running command returns an error.
+// the session is not started again
+func (s *Session) Close() {
+	s.procMu.Lock()
+	s.closed = true
+	if s.pgid != 0 {
+		syscall.Kill(-s.pgid, syscall.SIGKILL)
+	}
+	s.procMu.Unlock()
+
+	if s.mu.TryLock() {
+		defer s.mu.Unlock()
+		s.stop()
+	}
+	// otherwise the running command stops the session when it sees the shell exited
+}
+
+func (s *Session) start() error {
+	if s.cmd != nil {
+		return nil
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	s.marker = "__NOTCH_" + hex.EncodeToString(nonce)
+
+	cmd := exec.Command(s.program)
+	// the commands of the session are killed with the shell
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	s.procMu.Lock()
+	if s.closed {
+		s.procMu.Unlock()
+		return fmt.Errorf("the %s session is closed", s.name)
+	}
+	if err := cmd.Start(); err != nil {
+		s.procMu.Unlock()
+		return fmt.Errorf("starting %s: %s", s.program, err.Error())
+	}
+	s.pgid = cmd.Process.Pid
+	s.procMu.Unlock()
+	s.cmd = cmd
+	s.stdin = stdin
+	s.stdout = bufio.NewReader(stdout)
+	s.stderr = bufio.NewReader(stderr)
+
+	res, err := s.exec("")
+	if err != nil {
+		s.stop()
+		return err
+	}
+	s.initial = res.Env
+	return nil
+}
+
+func (s *Session) stop() {
+	if s.cmd == nil {
+		return
+	}
+	s.stdin.Close()
+	s.procMu.Lock()
+	syscall.Kill(-s.pgid, syscall.SIGKILL)
+	s.pgid = 0
+	s.procMu.Unlock()
+	s.cmd.Wait()
+	s.cmd = nil
+	s.last = nil
+}
+
+func (s *Session) exec(command string) (*Result, error) {
+	script, err := os.CreateTemp("", "notch-shell-*.sh")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(script.Name())
+	if _, err := script.WriteString(command + "\n"); err != nil {
+		script.Close()
+		return nil, err
+	}
+	script.Close()
+
+	// the exit code is kept before the markers are written, stdin is not shared with the commands
+	frame := fmt.Sprintf(". %s </dev/null\n"+
+		"__notch_status=$?\n"+
+		"printf '\\n%s %%d %%s\\n' \"$__notch_status\" \"$PWD\"\n"+
+		"env\n"+
+		"printf '%s_env\\n'\n"+
+		"printf '\\n%s\\n' >&2\n", quote(script.Name()), s.marker, s.marker, s.marker)
+	if _, err := io.WriteString(s.stdin, frame); err != nil {
+		return nil, fmt.Errorf("the shell session exited")
+	}
+
+	type read struct {
+		res *Result
+		err error
+	}
+	done := make(chan read, 1)
+	go func() {
+		res, err := s.read()
+		done <- read{res, err}
+	}()
+
+	var timeout <-chan time.Time
+	if s.timeout > 0 {
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		s.last = r.res
+		return r.res, nil
+	case <-timeout:
+		// the reads end when the session is stopped
+		return nil, fmt.Errorf("the command did not finish within %s", s.timeout)
+	}
+}
+
+// reads both outputs at once, a full stderr pipe would block the command otherwise
+func (s *Session) read() (*Result, error) {
+	var (
+		stderr    string
+		stderrErr error
+		wg        sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stderr, _, stderrErr = readFrame(s.stderr, s.marker)
+	}()
+
+	stdout, status, err := readFrame(s.stdout, s.marker)
+	env := ""
+	if err == nil {
+		env, _, err = readFrame(s.stdout, s.marker+"_env")
+	}
+	wg.Wait()
+	if err == nil {
+		err = stderrErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the shell session exited")
+	}
+
+	// exit code and directory
+	fields := strings.SplitN(strings.TrimSpace(status), " ", 2)
+	code, err := strconv.Atoi(fields[0])
+	if err != nil || len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected status line '%s'", status)
+	}
+
+	return &Result{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: code,
+		Dir:      fields[1],
+		Env:      parseEnv(env),
+	}, nil
+}
+
+// returns the lines before the marker without the newline written before the marker,
+// and the rest of the marker line
+func readFrame(r *bufio.Reader, marker string) (string, string, error) {
+	var sb strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return sb.String(), "", err
+		}
+		if rest, found := strings.CutPrefix(line, marker); found && (rest == "\n" || strings.HasPrefix(rest, " ")) {
+			return strings.TrimSuffix(sb.String(), "\n"), strings.TrimSuffix(rest, "\n"), nil
+		}
+		sb.WriteString(line)
+	}
+}
+
+// lines of env, a line not starting with a name continues the previous value
+func parseEnv(output string) map[string]string {
+	env := map[string]string{}
+	last := ""
+	for _, line := range strings.Split(output, "\n") {
+		if variableName.MatchString(line) {
+			key, value, _ := strings.Cut(line, "=")
+			env[key] = value
+			last = key
+		} else if last != "" {
+			env[last] += "\n" + line
+		}
+	}
+	return env
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
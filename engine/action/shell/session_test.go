@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+func Test_SessionState(t *testing.T) {
+	sessions := NewSessions("/bin/sh", 5*time.Second)
+	defer sessions.Close()
+	session := sessions.Get(DEFAULT_SESSION)
+	dir := t.TempDir()
+
+	if _, err := session.Run("cd " + dir + " && export NOTCH_TEST=one"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := session.Run("printf %s \"$NOTCH_TEST\"; echo oops >&2; false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stdout != "one" || res.Stderr != "oops\n" || res.ExitCode != 1 || res.Dir != dir {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if got := session.Describe(res); got != "default session in "+dir+", environment changed: NOTCH_TEST=one" {
+		t.Fatalf("unexpected description %q", got)
+	}
+
+	// the commands do not read the input of the session
+	if res, err := session.Run("cat; echo done"); err != nil || res.Stdout != "done\n" {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+
+	// a syntax error does not reach the session
+	res, err = session.Run("echo 'unterminated")
+	if err != nil || res.ExitCode == 0 || res.Dir != dir {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+
+	// other sessions have their own state
+	if res, err := sessions.Get("other").Run("echo \"$NOTCH_TEST\""); err != nil || res.Stdout != "\n" {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+}
+
+func Test_SessionRestart(t *testing.T) {
+	sessions := NewSessions("/bin/sh", 200*time.Millisecond)
+	defer sessions.Close()
+	session := sessions.Get(DEFAULT_SESSION)
+
+	if _, err := session.Run("exit 3"); err == nil {
+		t.Fatal("the exit of the session is not reported")
+	}
+	if _, err := session.Run("sleep 5"); err == nil || !strings.Contains(err.Error(), "did not finish") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if res, err := session.Run("echo back"); err != nil || res.Stdout != "back\n" {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+}
+
+func Test_SessionShell(t *testing.T) {
+	shell := NewSessionShell(NewSessions("/bin/sh", 5*time.Second))
+	defer shell.(*Shell).sessions.Close()
+	s := storage.NewStorage("shell", types.UNTAGGED, func(events.DisplayEvent) {})
+	dir := t.TempDir()
+
+	if shell.ParallelSafe() {
+		t.Fatal("session commands must run in order")
+	}
+	shell.Run(s, map[string]string{"session": "build"}, "cd "+dir)
+	if out := shell.Run(s, map[string]string{"session": "build"}, "pwd"); out != dir+"\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if e, found := s.GetEntry("build"); !found || e.Data != "build session in "+dir {
+		t.Fatalf("unexpected storage %v", s.GetEntryList())
+	}
+}
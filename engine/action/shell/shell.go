@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
@@ -17,21 +18,36 @@ import (
 //go:embed shell.prompt
 var shellPrompt string
 
+//go:embed session.prompt
+var sessionPrompt string
+
 //go:embed ns.prompt
 var nsPrompt string
 
 type Shell struct {
+	// the commands run in persistent sessions if set
+	sessions *Sessions
 }
 
 func NewShell() action.Action {
 	return &Shell{}
 }
 
+// the commands share the directory and the environment of their session
+func NewSessionShell(sessions *Sessions) action.Action {
+	return &Shell{
+		sessions: sessions,
+	}
+}
+
 func (s *Shell) Name() string {
 	return "shell"
 }
 
 func (s *Shell) Description() string {
+	if s.sessions != nil {
+		return sessionPrompt
+	}
 	return shellPrompt
 }
 
@@ -39,6 +55,10 @@ func (s *Shell) Run(storage *storage.Storage, attributes map[string]string, payl
 	command := payload
 	log.Printf("Executing command: %s", command)
 
+	if s.sessions != nil {
+		return s.runInSession(storage, attributes["session"], command)
+	}
+
 	cmd := exec.CommandContext(context.Background(), "/bin/sh", "-c", command)
 
 	var stdout, stderr bytes.Buffer
@@ -54,6 +74,28 @@ func (s *Shell) Run(storage *storage.Storage, attributes map[string]string, payl
 	return formatOutput(stdout.String(), stderr.String(), 0)
 }
 
+func (s *Shell) runInSession(storage *storage.Storage, name string, command string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DEFAULT_SESSION
+	}
+
+	session := s.sessions.Get(name)
+	res, err := session.Run(command)
+	if err != nil {
+		log.Printf("Session %s error: %v", name, err)
+		if storage != nil {
+			storage.DelTagged(name)
+		}
+		return fmt.Sprintf("%s, the %s session starts again with the next command", err.Error(), name)
+	}
+
+	if storage != nil {
+		storage.AddData(name, session.Describe(res))
+	}
+	return formatOutput(res.Stdout, res.Stderr, res.ExitCode)
+}
+
 func formatOutput(stdout, stderr string, exitCode int) string {
 	result := stdout
 	if stderr != "" {
@@ -86,6 +128,15 @@ func (s *Shell) RequiresUserConfirmation() bool {
 	return true
 }
 
+func (s *Shell) ParallelSafe() bool {
+	// a command can read what the previous one wrote
+	return false
+}
+
+func (s *Shell) Effect() action.Effect {
+	return action.WRITE
+}
+
 func (s *Shell) GetNamespace() types.NamespaceType {
 	return types.SHELL
 }
@@ -0,0 +1,70 @@
+package tasklet
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed complete.prompt
+var completePrompt string
+
+type Complete struct {
+}
+
+func NewComplete() action.Action {
+	return &Complete{}
+}
+
+func (c *Complete) Name() string {
+	return "task_complete"
+}
+
+func (c *Complete) Description() string {
+	return completePrompt
+}
+
+func (c *Complete) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	storage.AddData(COMPLETE_TAG, payload)
+	return "task complete"
+}
+
+func (c *Complete) Timeout() *time.Duration {
+	return nil
+}
+
+func (c *Complete) ExamplePayload() *string {
+	p := "brief report of the results"
+	return &p
+}
+
+func (c *Complete) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (c *Complete) RequiredVariables() []*string {
+	return nil
+}
+
+func (c *Complete) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (c *Complete) ParallelSafe() bool {
+	return false
+}
+
+func (c *Complete) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (c *Complete) GetNamespace() types.NamespaceType {
+	return types.TASKLET
+}
+
+func (c *Complete) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,76 @@
+package tasklet
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed impossible.prompt
+var impossiblePrompt string
+
+// storage keys read by the engine to finish the run
+const (
+	COMPLETE_TAG   = "complete"
+	IMPOSSIBLE_TAG = "impossible"
+)
+
+type Impossible struct {
+}
+
+func NewImpossible() action.Action {
+	return &Impossible{}
+}
+
+func (i *Impossible) Name() string {
+	return "task_impossible"
+}
+
+func (i *Impossible) Description() string {
+	return impossiblePrompt
+}
+
+func (i *Impossible) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	storage.AddData(IMPOSSIBLE_TAG, payload)
+	return "task set as impossible"
+}
+
+func (i *Impossible) Timeout() *time.Duration {
+	return nil
+}
+
+func (i *Impossible) ExamplePayload() *string {
+	p := "brief report on why the task is not possible"
+	return &p
+}
+
+func (i *Impossible) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (i *Impossible) RequiredVariables() []*string {
+	return nil
+}
+
+func (i *Impossible) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (i *Impossible) ParallelSafe() bool {
+	return false
+}
+
+func (i *Impossible) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (i *Impossible) GetNamespace() types.NamespaceType {
+	return types.TASKLET
+}
+
+func (i *Impossible) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -1,87 +1,193 @@
 package tasklet
 
 import (
+	"bytes"
+	"context"
 	_ "embed"
+	"fmt"
+	"log"
 	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/runetale/notch/engine/action"
 	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/task"
 	"github.com/runetale/notch/types"
 )
 
 //go:embed ns.prompt
 var nsPrompt string
 
+// the payload is substituted where the tool has $PAYLOAD, appended otherwise
+const PAYLOAD_VARIABLE = "PAYLOAD"
+
+// a tool running longer than its timeout is stopped
+const DEFAULT_TIMEOUT = 10 * time.Minute
+
+// $NAME or $NAME||default, the default ends at the next space
+var variableExpr = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)(\|\|(\S+))?`)
+
+// an action defined by the functions of the task, the tool is a command template
 type Tasklet struct {
 	name             string
 	description      string
+	namespace        types.NamespaceType
+	nsDescription    string
 	workingDirectory string
-	maxShownOutput   uint32
-	args             map[string]string
+	maxShownOutput   int
 	examplePayload   *string
-	timeout          string
 	tool             string
-	storageType      types.StorageType
-	predefined       *map[string]string
+	timeout          time.Duration
+	parallelSafe     bool
 }
 
-// TODO: implement complete and impossible
-func NewTasklet() action.Action {
-	return &Tasklet{
-		name:        "tasklet",
-		storageType: types.UNTAGGED,
-		predefined:  nil,
+func NewTasklet(fn *task.Function, ac task.Action, workingDirectory string) action.Action {
+	t := &Tasklet{
+		name:             ac.Name,
+		description:      ac.Description,
+		namespace:        types.NamespaceType(fn.Name),
+		nsDescription:    fn.Description,
+		workingDirectory: workingDirectory,
+		maxShownOutput:   ac.MaxShownOutput,
+		tool:             ac.Tool,
+		timeout:          DEFAULT_TIMEOUT,
+		parallelSafe:     fn.ParallelSafe,
+	}
+	if ac.Timeout > 0 {
+		t.timeout = time.Duration(ac.Timeout) * time.Second
 	}
+	if ac.ExamplePayload != "" {
+		t.examplePayload = &ac.ExamplePayload
+	}
+	return t
 }
 
 func (s *Tasklet) Name() string {
 	return s.name
 }
 
-func (s *Tasklet) ExamplePayload() *string {
-	p := "brief report on why the task is not possible"
-	return &p
+func (s *Tasklet) Description() string {
+	return s.description
 }
 
-func (s *Tasklet) ExampleAttributes() map[string]string {
-	return nil
+func (s *Tasklet) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	command, substituted := expand(s.tool, payload)
+	if !substituted && strings.TrimSpace(payload) != "" {
+		command = fmt.Sprintf("%s %s", command, payload)
+	}
+	log.Printf("Executing %s: %s", s.name, command)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Dir = s.workingDirectory
+	// the processes started by the tool are stopped with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// processes escaping the group may keep the output open after it is killed
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		if cmd.ProcessState == nil {
+			return err.Error()
+		}
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		output += fmt.Sprintf("\nSTDERR: %s\n", stderr.String())
+	}
+	if ctx.Err() != nil {
+		output += fmt.Sprintf("\nTIMEOUT: stopped after %s", s.timeout)
+	} else if exitCode != 0 {
+		output += fmt.Sprintf("\nEXIT CODE: %d", exitCode)
+	}
+	return truncate(output, s.maxShownOutput)
 }
 
-func (s *Tasklet) Predefined() *map[string]string {
-	return s.predefined
+// replaces the variables of the tool with the environment or their default,
+// the variables were set by the state when they are missing
+func expand(tool, payload string) (string, bool) {
+	substituted := false
+	command := variableExpr.ReplaceAllStringFunc(tool, func(expr string) string {
+		match := variableExpr.FindStringSubmatch(expr)
+		if match[1] == PAYLOAD_VARIABLE {
+			substituted = true
+			return payload
+		}
+		if value, exists := os.LookupEnv(match[1]); exists {
+			return value
+		}
+		return match[3]
+	})
+	return command, substituted
 }
 
-func (s *Tasklet) StorageType() types.StorageType {
-	return s.storageType
+// keeps the first max characters of the output, 0 keeps everything
+func truncate(output string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(output) <= max {
+		return output
+	}
+	runes := []rune(output)
+	return fmt.Sprintf("%s\n... (output truncated, %d of %d characters shown)", string(runes[:max]), max, len(runes))
 }
 
-func (s *Tasklet) Description() string {
-	filepath := "shell.prompt"
-	data, _ := os.ReadFile(filepath)
-	return string(data)
+// the tool stops itself, the engine only waits a little longer
+func (s *Tasklet) Timeout() *time.Duration {
+	timeout := s.timeout + 5*time.Second
+	return &timeout
 }
 
-func (s *Tasklet) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
-	return "run"
+func (s *Tasklet) ExamplePayload() *string {
+	return s.examplePayload
 }
 
-func (s *Tasklet) Timeout() *time.Duration {
+func (s *Tasklet) ExampleAttributes() map[string]string {
 	return nil
 }
 
+// expressions of the tool variables, resolved by the state before the run
 func (s *Tasklet) RequiredVariables() []*string {
-	return nil
+	var required []*string
+	for _, match := range variableExpr.FindAllStringSubmatch(s.tool, -1) {
+		if match[1] == PAYLOAD_VARIABLE {
+			continue
+		}
+		expr := strings.TrimPrefix(match[0], "$")
+		required = append(required, &expr)
+	}
+	return required
 }
 
 func (s *Tasklet) RequiresUserConfirmation() bool {
 	return true
 }
 
+// declared by the function in the task
+func (s *Tasklet) ParallelSafe() bool {
+	return s.parallelSafe
+}
+
+func (s *Tasklet) Effect() action.Effect {
+	return action.WRITE
+}
+
 func (s *Tasklet) GetNamespace() types.NamespaceType {
-	return types.TASKLET
+	return s.namespace
 }
 
 func (s *Tasklet) NamespaceDescription() string {
-	return nsPrompt
+	return s.nsDescription
 }
@@ -0,0 +1,93 @@
+package tasklet
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/task"
+)
+
+func Test_Expand(t *testing.T) {
+	t.Setenv("NOTCH_TEST_HOST", "10.0.0.5")
+
+	command, substituted := expand("ssh -l $NOTCH_TEST_USER||notch $NOTCH_TEST_HOST", "whoami")
+	if command != "ssh -l notch 10.0.0.5" || substituted {
+		t.Fatalf("unexpected command %q", command)
+	}
+	command, substituted = expand("curl -s $NOTCH_TEST_HOST/$PAYLOAD | head", "index.html")
+	if command != "curl -s 10.0.0.5/index.html | head" || !substituted {
+		t.Fatalf("unexpected command %q", command)
+	}
+}
+
+func Test_RequiredVariables(t *testing.T) {
+	fn := &task.Function{Name: "Commands"}
+	ac := NewTasklet(fn, task.Action{Name: "command", Tool: "ssh $KALI||notch@kali.local $PAYLOAD"}, ".")
+	required := ac.RequiredVariables()
+	if len(required) != 1 || *required[0] != "KALI||notch@kali.local" {
+		t.Fatalf("unexpected variables %v", required)
+	}
+	if ac.ExamplePayload() != nil || string(ac.GetNamespace()) != "Commands" || ac.ParallelSafe() {
+		t.Fatalf("unexpected action %+v", ac)
+	}
+
+	// the function declares its actions can run concurrently
+	scanners := &task.Function{Name: "Scanners", ParallelSafe: true}
+	if !NewTasklet(scanners, task.Action{Name: "scan", Tool: "nmap $PAYLOAD"}, ".").ParallelSafe() {
+		t.Fatal("the declaration of the function is ignored")
+	}
+}
+
+func Test_RunTasklet(t *testing.T) {
+	fn := &task.Function{Name: "Commands"}
+	ac := NewTasklet(fn, task.Action{Name: "say", Tool: "echo hello", MaxShownOutput: 8, ExamplePayload: "world"}, t.TempDir())
+	if *ac.ExamplePayload() != "world" {
+		t.Fatalf("unexpected example payload")
+	}
+
+	out := ac.Run(nil, nil, "world")
+	if !strings.HasPrefix(out, "hello wo\n... (output truncated, 8 of 12 characters shown)") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	out = NewTasklet(fn, task.Action{Name: "fail", Tool: "exit 3"}, ".").Run(nil, nil, "")
+	if !strings.Contains(out, "EXIT CODE: 3") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func Test_TaskletTimeout(t *testing.T) {
+	fn := &task.Function{Name: "Scanners"}
+	ac := NewTasklet(fn, task.Action{Name: "scan", Tool: "echo started; sleep 30", Timeout: 1}, t.TempDir())
+
+	start := time.Now()
+	out := ac.Run(nil, nil, "")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the tool was not stopped, ran for %s", elapsed)
+	}
+	if !strings.HasPrefix(out, "started\n") || !strings.HasSuffix(out, "TIMEOUT: stopped after 1s") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if *ac.Timeout() <= time.Second {
+		t.Fatalf("the engine must wait for the tool to stop itself, got %s", *ac.Timeout())
+	}
+
+	// the processes started by the tool are stopped too
+	ac = NewTasklet(fn, task.Action{Name: "scan", Tool: "sleep 30 & echo $!; wait", Timeout: 1}, t.TempDir())
+	out = ac.Run(nil, nil, "")
+	pid, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(out, "\n", 2)[0]))
+	if err != nil {
+		t.Fatalf("unexpected output %q", out)
+	}
+	process, _ := os.FindProcess(pid)
+	for i := 0; process.Signal(syscall.Signal(0)) == nil; i++ {
+		if i == 20 {
+			t.Fatalf("the child %d of the tool is still running", pid)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
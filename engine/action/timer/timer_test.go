@@ -0,0 +1,49 @@
+package timer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseSeconds(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"30", 30 * time.Second, true},
+		{" 0.5 ", 500 * time.Millisecond, true},
+		{"0", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"7200", 0, false},
+	}
+	for _, tc := range cases {
+		got, err := parseSeconds(tc.value)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("%q: expected %v %v, got %v %v", tc.value, tc.want, tc.ok, got, err)
+		}
+	}
+}
+
+func Test_WaitUntil(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		os.WriteFile(ready, nil, 0644)
+	}()
+
+	w := NewWaitUntil()
+	out := w.Run(nil, map[string]string{"timeout": "5", "interval": "0.05"}, "test -f "+ready)
+	if !strings.HasPrefix(out, "condition met") {
+		t.Fatalf("expected the condition to be met, got %q", out)
+	}
+
+	out = w.Run(nil, map[string]string{"timeout": "0.2", "interval": "0.05"}, "echo down; exit 1")
+	if !strings.HasPrefix(out, "condition not met") || !strings.Contains(out, "down") {
+		t.Fatalf("expected a timeout with the last output, got %q", out)
+	}
+}
@@ -0,0 +1,96 @@
+package timer
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed wait.prompt
+var waitPrompt string
+
+//go:embed ns.prompt
+var nsPrompt string
+
+// longest wait of a single action
+const maxWait = time.Hour
+
+type Wait struct {
+}
+
+func NewWait() action.Action {
+	return &Wait{}
+}
+
+func (w *Wait) Name() string {
+	return "wait"
+}
+
+func (w *Wait) Description() string {
+	return waitPrompt
+}
+
+func (w *Wait) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	duration, err := parseSeconds(payload)
+	if err != nil {
+		return err.Error()
+	}
+	time.Sleep(duration)
+	return fmt.Sprintf("waited %v", duration)
+}
+
+// a positive number of seconds, up to maxWait
+func parseSeconds(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid number of seconds '%s'", value)
+	}
+	duration := time.Duration(seconds * float64(time.Second))
+	if duration > maxWait {
+		return 0, fmt.Errorf("%s seconds is longer than the maximum of %v", value, maxWait)
+	}
+	return duration, nil
+}
+
+func (w *Wait) Timeout() *time.Duration {
+	return nil
+}
+
+func (w *Wait) ExamplePayload() *string {
+	p := "30"
+	return &p
+}
+
+func (w *Wait) ExampleAttributes() map[string]string {
+	return nil
+}
+
+func (w *Wait) RequiredVariables() []*string {
+	return nil
+}
+
+func (w *Wait) RequiresUserConfirmation() bool {
+	return false
+}
+
+func (w *Wait) ParallelSafe() bool {
+	return true
+}
+
+func (w *Wait) Effect() action.Effect {
+	return action.INTERNAL
+}
+
+func (w *Wait) GetNamespace() types.NamespaceType {
+	return types.TIME
+}
+
+func (w *Wait) NamespaceDescription() string {
+	return nsPrompt
+}
@@ -0,0 +1,126 @@
+package timer
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed wait_until.prompt
+var waitUntilPrompt string
+
+const defaultInterval = 5 * time.Second
+
+type WaitUntil struct {
+}
+
+func NewWaitUntil() action.Action {
+	return &WaitUntil{}
+}
+
+func (w *WaitUntil) Name() string {
+	return "wait_until"
+}
+
+func (w *WaitUntil) Description() string {
+	return waitUntilPrompt
+}
+
+func (w *WaitUntil) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	command := strings.TrimSpace(payload)
+	if command == "" {
+		return "a command to check is required"
+	}
+	timeout, err := parseSeconds(attributes["timeout"])
+	if err != nil {
+		return err.Error()
+	}
+	interval := defaultInterval
+	if value, found := attributes["interval"]; found {
+		if interval, err = parseSeconds(value); err != nil {
+			return err.Error()
+		}
+	}
+
+	started := time.Now()
+	deadline := started.Add(timeout)
+	checks := 0
+	for {
+		checks++
+		output, ok := check(command, time.Until(deadline))
+		if ok {
+			return fmt.Sprintf("condition met after %v and %d checks\n%s", time.Since(started).Round(time.Second), checks, output)
+		}
+		// the last check is done at the deadline
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return fmt.Sprintf("condition not met after %v and %d checks, last output:\n%s", timeout, checks, output)
+		}
+		time.Sleep(min(interval, remain))
+	}
+}
+
+// runs the command, it succeeds with a zero exit code
+func check(command string, timeout time.Duration) (string, bool) {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "/bin/sh", "-c", command).CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output) + "(check timed out)", false
+	}
+	if err != nil {
+		return string(output) + err.Error(), false
+	}
+	return string(output), true
+}
+
+func (w *WaitUntil) Timeout() *time.Duration {
+	return nil
+}
+
+func (w *WaitUntil) ExamplePayload() *string {
+	p := "curl -sf http://localhost:8080/health"
+	return &p
+}
+
+func (w *WaitUntil) ExampleAttributes() map[string]string {
+	attr := map[string]string{}
+	attr["timeout"] = "120"
+	return attr
+}
+
+func (w *WaitUntil) RequiredVariables() []*string {
+	return nil
+}
+
+// the check is a shell command
+func (w *WaitUntil) RequiresUserConfirmation() bool {
+	return true
+}
+
+func (w *WaitUntil) ParallelSafe() bool {
+	return false
+}
+
+func (w *WaitUntil) Effect() action.Effect {
+	return action.WRITE
+}
+
+func (w *WaitUntil) GetNamespace() types.NamespaceType {
+	return types.TIME
+}
+
+func (w *WaitUntil) NamespaceDescription() string {
+	return nsPrompt
+}
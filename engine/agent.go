@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/runetale/notch/engine/namespace"
+	"github.com/runetale/notch/engine/operator"
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
+)
+
+// the namespaces and the functions a sub agent can use, among the ones of its parent.
+// it can't delegate again, and always has the tasklet namespace to complete its task
+func childNamespaces(groups []*namespace.Namespace, fns []*task.Function, namespaces []string) ([]string, []string) {
+	allowed := func(ns string) bool {
+		return len(namespaces) == 0 || slices.Contains(namespaces, ns)
+	}
+	// the namespaces of the functions are created from the functions of the child task
+	isFunction := map[string]bool{}
+	for _, fn := range fns {
+		isFunction[fn.Name] = true
+	}
+	using := []string{}
+	functions := []string{}
+	for _, group := range groups {
+		ns := string(group.Type())
+		switch {
+		case group.Type() == types.AGENT || !allowed(ns):
+		case isFunction[ns]:
+			functions = append(functions, ns)
+		case group.Type() != types.TASKLET:
+			using = append(using, ns)
+		}
+	}
+	return using, functions
+}
+
+// the engine of a sub agent, it stops when the budget of the run is exhausted
+func (e *Engine) newChild(goal string, using []string, functions []string, maxSteps uint) *Engine {
+	// the operator messages are kept for the parent, the child has its own queue
+	child := newEngine(e.channel, e.task.NewChild(goal, using, functions), e.factory, maxSteps, e.nativeTool, "", e.confirmer, e.hooks, operator.NewQueue())
+	child.state.SetBudget(e.state.GetBudget())
+	return child
+}
+
+// runs a sub agent with its own goal and history until it completes,
+// gives up or runs out of steps, and returns its final report
+func (e *Engine) spawn(goal string, namespaces []string, maxSteps uint) string {
+	using, functions := childNamespaces(e.state.GetNamespaces(), e.task.GetFunctions(), namespaces)
+	if len(using) == 0 && len(functions) == 0 {
+		return fmt.Sprintf("none of the namespaces %s are available to the sub agent", strings.Join(namespaces, ","))
+	}
+	using = append(using, string(types.TASKLET))
+
+	child := e.newChild(goal, using, functions, maxSteps)
+	e.state.OnEvent(events.NewSubAgentEvent(goal, nil))
+
+	go child.automaton()
+	select {
+	case <-child.Done():
+	case <-e.Done():
+		child.Stop()
+	}
+
+	e.state.AddMetrics(child.state.GetMetrics())
+	e.plan = append(e.plan, child.plan...)
+
+	result := child.Result()
+	report := fmt.Sprintf("sub agent %s", result.Display())
+	e.state.OnEvent(events.NewSubAgentEvent(goal, &report))
+
+	// keep what the sub agent found even if it did not complete
+	if memories := child.state.GetStorage("memories"); memories != nil && !memories.IsEmpty() {
+		var sb strings.Builder
+		sb.WriteString(report)
+		sb.WriteString("\n\nsub agent memories:\n")
+		for key, entry := range memories.GetEntryList() {
+			sb.WriteString(fmt.Sprintf("- %s=%s\n", key, entry.Data))
+		}
+		report = sb.String()
+	}
+
+	return report
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/engine/namespace"
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
+)
+
+func Test_ChildNamespaces(t *testing.T) {
+	fns := []*task.Function{
+		{Name: "Commands", Actions: []task.Action{{Name: "command", Tool: "echo"}}},
+		{Name: "Scanners", Actions: []task.Action{{Name: "scan", Tool: "echo"}}},
+	}
+	tk := &task.Task{Functions: fns}
+	groups := []*namespace.Namespace{}
+	for _, ns := range []string{"shell", "memory", "agent", "tasklet", "Commands", "Scanners"} {
+		groups = append(groups, namespace.NewNamespace(types.NamespaceType(ns), tk))
+	}
+
+	cases := []struct {
+		namespaces []string
+		using      []string
+		functions  []string
+	}{
+		{nil, []string{"shell", "memory"}, []string{"Commands", "Scanners"}},
+		{[]string{"shell", "memory"}, []string{"shell", "memory"}, []string{}},
+		{[]string{"memory", "Scanners", "agent"}, []string{"memory"}, []string{"Scanners"}},
+	}
+	for _, c := range cases {
+		using, functions := childNamespaces(groups, fns, c.namespaces)
+		if !slices.Equal(using, c.using) || !slices.Equal(functions, c.functions) {
+			t.Fatalf("%v: unexpected namespaces %v functions %v", c.namespaces, using, functions)
+		}
+	}
+
+	// the child only builds the allowed functions
+	child := tk.NewChild("goal", []string{"tasklet"}, []string{"Scanners"})
+	if len(child.GetFunctions()) != 1 || child.GetFunctions()[0].Name != "Scanners" {
+		t.Fatalf("unexpected functions %v", child.GetFunctions())
+	}
+}
+
+func Test_ChildBudget(t *testing.T) {
+	tk := newTestTask("memory")
+	tk.Budget = &task.Budget{Tokens: 100}
+	e := newTestEngine(t, tk, 0)
+
+	child := e.newChild("scan the host", []string{string(types.TASKLET)}, nil, 10)
+	// the operator messages typed during the delegation stay with the parent
+	e.Operator().Push("skip port 22")
+	if len(child.Operator().Drain()) != 0 || len(e.Operator().Drain()) != 1 {
+		t.Fatal("the child consumed the operator messages of its parent")
+	}
+	child.state.GetBudget().AddUsage(llm.Usage{PromptTokens: 60})
+	if e.state.GetBudget().GetTokens() != 60 {
+		t.Fatalf("the parent did not see the usage of the child, %d tokens", e.state.GetBudget().GetTokens())
+	}
+
+	// the parent ran out, the child stops before its first step
+	e.state.GetBudget().AddUsage(llm.Usage{PromptTokens: 40})
+	child.automaton()
+	if result := child.Result(); result == nil || result.Status != BUDGET_EXHAUSTED {
+		t.Fatalf("unexpected result %v", result)
+	}
+}
+
+func Test_ChildClosesSessions(t *testing.T) {
+	tk := newTestTask("shell")
+	tk.Shell = &task.Shell{Persistent: true}
+	e := newTestEngine(t, tk, 0)
+
+	child := e.newChild("scan the host", []string{"shell", string(types.TASKLET)}, nil, 10)
+	sh := child.state.GetAciton("shell")
+	out := sh.Run(child.state.GetStorage("shell"), nil, "echo $$")
+	pid, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	child.finish(COMPLETED, "done")
+	if process, _ := os.FindProcess(pid); process.Signal(syscall.Signal(0)) == nil {
+		t.Fatal("the shell session is still running after the sub agent ended")
+	}
+}
+
+func Test_FinishWhileRunning(t *testing.T) {
+	tk := newTestTask("shell")
+	tk.Shell = &task.Shell{Persistent: true}
+	e := newTestEngine(t, tk, 0)
+
+	sh := e.state.GetAciton("shell")
+	ran := make(chan string)
+	go func() {
+		ran <- sh.Run(e.state.GetStorage("shell"), nil, "sleep 3600")
+	}()
+	// the session is started by its first command
+	time.Sleep(500 * time.Millisecond)
+
+	finished := make(chan bool)
+	go func() {
+		e.finish(STOPPED, "interrupted")
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("finish waited for the running command")
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the running command did not stop with the session")
+	}
+}
@@ -9,6 +9,7 @@ type MessageType string
 const (
 	AGETNT   MessageType = "agent"
 	FEEDBACK MessageType = "feedback"
+	OPERATOR MessageType = "operator"
 )
 
 type Message struct {
@@ -23,6 +24,8 @@ func (m *Message) Display() string {
 		return fmt.Sprintf("[agent]\n\n%s\n", *m.Response)
 	case FEEDBACK:
 		return fmt.Sprintf("[feedback]\n\n%s\n", *m.Response)
+	case OPERATOR:
+		return fmt.Sprintf("[operator]\n\n%s\n", *m.Response)
 	default:
 		return ""
 	}
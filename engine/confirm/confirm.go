@@ -0,0 +1,66 @@
+// user confirmation of invocations before the engine executes them
+package confirm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+type ConfirmerType string
+
+const (
+	STDIN   ConfirmerType = "stdin"
+	APPROVE ConfirmerType = "approve"
+	DENY    ConfirmerType = "deny"
+	HTTP    ConfirmerType = "http"
+)
+
+// called by the engine for every action that requires user confirmation.
+// returns true if the invocation may be executed
+type Confirmer interface {
+	Confirm(inv *chat.Invocation) (bool, error)
+}
+
+// asks the operator on the terminal, empty input is treated as yes
+type StdinConfirmer struct {
+	input func(prompt string) string
+}
+
+func NewStdinConfirmer(input func(prompt string) string) Confirmer {
+	return &StdinConfirmer{
+		input: input,
+	}
+}
+
+func (c *StdinConfirmer) Confirm(inv *chat.Invocation) (bool, error) {
+	inp := "nope"
+	for inp != "" && inp != "n" && inp != "y" {
+		log.Println("invocation by y or n")
+		inp = c.input(fmt.Sprintf("%s [Yn] ", inv.FunctionCallString()))
+		inp = strings.ToLower(inp)
+	}
+	return inp != "n", nil
+}
+
+// always returns the same decision, for headless runs
+type AutoConfirmer struct {
+	approve bool
+}
+
+func NewAutoConfirmer(approve bool) Confirmer {
+	return &AutoConfirmer{
+		approve: approve,
+	}
+}
+
+func (c *AutoConfirmer) Confirm(inv *chat.Invocation) (bool, error) {
+	if c.approve {
+		log.Printf("auto approved %s", inv.FunctionCallString())
+	} else {
+		log.Printf("auto denied %s", inv.FunctionCallString())
+	}
+	return c.approve, nil
+}
@@ -0,0 +1,326 @@
+package confirm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+const pendingPage = `<!DOCTYPE html>
+<html>
+<head><title>notch approvals</title></head>
+<body>
+<h1>pending invocations</h1>
+{{range .Pending}}
+<div>
+  <pre>{{.Call}}</pre>
+  <form method="post" action="/approve/{{.ID}}?token={{$.Token}}" style="display:inline"><button>approve</button></form>
+  <form method="post" action="/deny/{{.ID}}?token={{$.Token}}" style="display:inline"><button>deny</button></form>
+</div>
+{{else}}
+<p>nothing to approve</p>
+{{end}}
+</body>
+</html>`
+
+// the invocation waiting for a decision, also the body posted to the webhook
+type Pending struct {
+	ID         string            `json:"id"`
+	Action     string            `json:"action"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Payload    *string           `json:"payload,omitempty"`
+	Call       string            `json:"call"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ApproveURL string            `json:"approve_url,omitempty"`
+	DenyURL    string            `json:"deny_url,omitempty"`
+
+	decision chan bool
+}
+
+// optional webhook response, an empty body means wait for the endpoint
+type webhookResponse struct {
+	Decision string `json:"decision"`
+}
+
+// exposes an approval endpoint and optionally posts each pending
+// invocation to a webhook, then waits for a decision until the timeout
+type HTTPConfirmer struct {
+	webhook string
+	timeout time.Duration
+	client  *http.Client
+
+	listener net.Listener
+	server   *http.Server
+	page     *template.Template
+
+	// required by every request to the endpoint, random for each run
+	token string
+
+	mu      sync.Mutex
+	pending map[string]*Pending
+}
+
+func NewHTTPConfirmer(addr, webhook string, timeout time.Duration) (*HTTPConfirmer, error) {
+	if addr == "" && webhook == "" {
+		return nil, errors.New("http confirmer requires an address or a webhook")
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &HTTPConfirmer{
+		token:   token,
+		webhook: webhook,
+		timeout: timeout,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		page:    template.Must(template.New("pending").Parse(pendingPage)),
+		pending: make(map[string]*Pending, 0),
+	}
+
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /{$}", c.handleIndex)
+		mux.HandleFunc("GET /pending", c.handlePending)
+		mux.HandleFunc("POST /approve/{id}", c.handleDecision(true))
+		mux.HandleFunc("POST /deny/{id}", c.handleDecision(false))
+
+		c.listener = listener
+		c.server = &http.Server{Handler: c.authorize(mux)}
+		go func() {
+			if err := c.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("confirmer server error %s", err.Error())
+			}
+		}()
+		log.Printf("waiting for approvals on http://%s/?token=%s", listener.Addr().String(), token)
+	}
+
+	return c, nil
+}
+
+func (c *HTTPConfirmer) Addr() string {
+	if c.listener == nil {
+		return ""
+	}
+	return c.listener.Addr().String()
+}
+
+// the token the requests to the endpoint must carry
+func (c *HTTPConfirmer) Token() string {
+	return c.token
+}
+
+func (c *HTTPConfirmer) Close() error {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.Close()
+}
+
+func (c *HTTPConfirmer) Confirm(inv *chat.Invocation) (bool, error) {
+	p, err := c.addPending(inv)
+	if err != nil {
+		return false, err
+	}
+	defer c.removePending(p.ID)
+
+	if c.webhook != "" {
+		approved, decided, err := c.notify(p)
+		if err != nil {
+			log.Printf("confirmer webhook error %s", err.Error())
+		}
+		if decided {
+			return approved, nil
+		}
+		if c.server == nil {
+			if err == nil {
+				err = fmt.Errorf("webhook gave no decision for %s", p.Call)
+			}
+			return false, err
+		}
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case approved := <-p.decision:
+		return approved, nil
+	case <-timer.C:
+		return false, fmt.Errorf("no decision for %s after %s", p.Call, c.timeout.String())
+	}
+}
+
+func (c *HTTPConfirmer) addPending(inv *chat.Invocation) (*Pending, error) {
+	// ids can't be guessed
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := &Pending{
+		ID:         id,
+		Action:     inv.Action,
+		Attributes: inv.Attributes,
+		Payload:    inv.Payload,
+		Call:       inv.FunctionCallString(),
+		CreatedAt:  time.Now(),
+		decision:   make(chan bool, 1),
+	}
+	if c.listener != nil {
+		base := fmt.Sprintf("http://%s", c.listener.Addr().String())
+		p.ApproveURL = fmt.Sprintf("%s/approve/%s?token=%s", base, id, c.token)
+		p.DenyURL = fmt.Sprintf("%s/deny/%s?token=%s", base, id, c.token)
+	}
+	c.pending[id] = p
+	return p, nil
+}
+
+func (c *HTTPConfirmer) removePending(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+func (c *HTTPConfirmer) listPending() []*Pending {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]*Pending, 0, len(c.pending))
+	for _, p := range c.pending {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// posts the pending invocation to the webhook.
+// the webhook may answer with a decision right away
+func (c *HTTPConfirmer) notify(p *Pending) (bool, bool, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return false, false, err
+	}
+
+	resp, err := c.client.Post(c.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return false, false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	var decision webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, false, nil
+	}
+
+	switch decision.Decision {
+	case "approve", "y", "yes":
+		return true, true, nil
+	case "deny", "n", "no":
+		return false, true, nil
+	}
+	return false, false, nil
+}
+
+// rejects the requests without the token of the run, and the ones sent by other sites,
+// e.g. a form of a page opened in the browser of the operator
+func (c *HTTPConfirmer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+			http.Error(w, "cross origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+			token = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *HTTPConfirmer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct {
+		Token   string
+		Pending []*Pending
+	}{c.token, c.listPending()}
+	if err := c.page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (c *HTTPConfirmer) handlePending(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.listPending())
+}
+
+func (c *HTTPConfirmer) handleDecision(approved bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		c.mu.Lock()
+		p, found := c.pending[id]
+		c.mu.Unlock()
+
+		if !found {
+			http.Error(w, "no pending invocation "+id, http.StatusNotFound)
+			return
+		}
+
+		select {
+		case p.decision <- approved:
+		default:
+			http.Error(w, "invocation already decided", http.StatusConflict)
+			return
+		}
+
+		// browsers submit the form, send them back to the list
+		if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			http.Redirect(w, r, "/?token="+c.token, http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,119 @@
+package confirm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+func Test_HTTPConfirmerEndpoint(t *testing.T) {
+	c, err := NewHTTPConfirmer("127.0.0.1:0", "", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	payload := "ls -la"
+	inv := chat.NewInvocation("shell", nil, &payload)
+
+	done := make(chan bool)
+	go func() {
+		approved, err := c.Confirm(inv)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- approved
+	}()
+
+	// wait for the invocation to be listed
+	var pending []*Pending
+	for i := 0; i < 100 && len(pending) == 0; i++ {
+		resp, err := http.Get(fmt.Sprintf("http://%s/pending?token=%s", c.Addr(), c.Token()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(resp.Body).Decode(&pending)
+		resp.Body.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 pending invocation, got %d", len(pending))
+	}
+
+	if len(pending[0].ID) != 32 {
+		t.Fatalf("guessable id %s", pending[0].ID)
+	}
+
+	// without the token or from another site
+	forged := []*http.Request{}
+	noToken, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/approve/%s", c.Addr(), pending[0].ID), nil)
+	forged = append(forged, noToken)
+	crossOrigin, _ := http.NewRequest(http.MethodPost, pending[0].ApproveURL, nil)
+	crossOrigin.Header.Set("Origin", "http://evil.example")
+	forged = append(forged, crossOrigin)
+	for _, req := range forged {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("forged request accepted with %s", resp.Status)
+		}
+	}
+
+	resp, err := http.Post(pending[0].ApproveURL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if !<-done {
+		t.Fatal("invocation should be approved")
+	}
+}
+
+func Test_HTTPConfirmerWebhook(t *testing.T) {
+	var received Pending
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"decision":"deny"}`))
+	}))
+	defer hook.Close()
+
+	c, err := NewHTTPConfirmer("", hook.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := "rm -rf /tmp/x"
+	approved, err := c.Confirm(chat.NewInvocation("shell", nil, &payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved {
+		t.Fatal("invocation should be denied by the webhook")
+	}
+	if received.Action != "shell" || *received.Payload != payload {
+		t.Fatalf("unexpected webhook body %+v", received)
+	}
+}
+
+func Test_HTTPConfirmerTimeout(t *testing.T) {
+	c, err := NewHTTPConfirmer("127.0.0.1:0", "", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	payload := "whoami"
+	approved, err := c.Confirm(chat.NewInvocation("shell", nil, &payload))
+	if err == nil || approved {
+		t.Fatal("invocation should be denied after the timeout")
+	}
+}
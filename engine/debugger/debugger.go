@@ -0,0 +1,162 @@
+// step mode, pauses the engine before every llm call and every invocation
+package debugger
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/serializer"
+)
+
+// what the engine does with a paused invocation
+type Decision string
+
+const (
+	// execute the invocation, possibly edited
+	EXECUTE Decision = "execute"
+	// record the invocation as skipped, the model sees it
+	SKIP Decision = "skip"
+	// forget the invocation, nothing is recorded
+	DROP Decision = "drop"
+	// stop the run
+	QUIT Decision = "quit"
+)
+
+const chatHelp = `commands:
+  c          continue to the invocations of this step
+  c N        continue without pausing for N steps
+  p          print the system prompt
+  m          print the chat history
+  q          stop the run`
+
+const invocationHelp = `commands:
+  x          execute the invocation
+  e          edit the invocation
+  s          skip the invocation, the model is told it was skipped
+  d          drop the invocation, nothing is recorded
+  c N        execute and continue without pausing for N steps
+  q          stop the run`
+
+// the engine runs a single step at a time, no locking is needed
+type Debugger struct {
+	input  func(prompt string) string
+	output io.Writer
+	step   uint
+	// no pause before this step
+	until uint
+}
+
+func NewDebugger(input func(prompt string) string, output io.Writer) *Debugger {
+	return &Debugger{
+		input:  input,
+		output: output,
+	}
+}
+
+// called before the llm call of the step, returns false if the run must be stopped
+func (d *Debugger) BeforeChat(step uint, option *chat.ChatOption) bool {
+	d.step = step
+	if d.running() {
+		return true
+	}
+
+	fmt.Fprintf(d.output, "\n[step %d] paused before the llm call, %d history messages\n", step, len(option.GetHistory()))
+	for {
+		cmd, arg := d.read("step > ")
+		switch cmd {
+		case "", "c":
+			if !d.continueFor(arg) {
+				continue
+			}
+			return true
+		case "p":
+			fmt.Fprintln(d.output, option.GetSystemPrompt())
+		case "m":
+			for _, m := range option.GetHistory() {
+				fmt.Fprintln(d.output, m.Display())
+			}
+		case "q":
+			return false
+		default:
+			fmt.Fprintln(d.output, chatHelp)
+		}
+	}
+}
+
+// called before an invocation is prepared, returns the invocation to execute
+func (d *Debugger) BeforeInvocation(inv *chat.Invocation) (*chat.Invocation, Decision) {
+	if d.running() {
+		return inv, EXECUTE
+	}
+
+	fmt.Fprintf(d.output, "\n[step %d] paused before %s\n", d.step, *serializer.SerializeInvocation(inv))
+	for {
+		cmd, arg := d.read("invocation > ")
+		switch cmd {
+		case "", "x":
+			return inv, EXECUTE
+		case "c":
+			if !d.continueFor(arg) {
+				continue
+			}
+			return inv, EXECUTE
+		case "e":
+			edited := d.edit()
+			if edited == nil {
+				continue
+			}
+			inv = edited
+			fmt.Fprintf(d.output, "edited to %s\n", *serializer.SerializeInvocation(inv))
+		case "s":
+			return inv, SKIP
+		case "d":
+			return inv, DROP
+		case "q":
+			return inv, QUIT
+		default:
+			fmt.Fprintln(d.output, invocationHelp)
+		}
+	}
+}
+
+// true if the next invocation pauses
+func (d *Debugger) Stepping() bool {
+	return !d.running()
+}
+
+func (d *Debugger) running() bool {
+	return d.step < d.until
+}
+
+// an empty argument continues to the next pause
+func (d *Debugger) continueFor(arg string) bool {
+	if arg == "" {
+		return true
+	}
+	n, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || n == 0 {
+		fmt.Fprintf(d.output, "invalid number of steps '%s'\n", arg)
+		return false
+	}
+	d.until = d.step + uint(n)
+	return true
+}
+
+// the replacement is written as an xml invocation on a single line
+func (d *Debugger) edit() *chat.Invocation {
+	raw := d.input("new invocation > ")
+	invocations := serializer.TryParse(raw)
+	if len(invocations) != 1 {
+		fmt.Fprintf(d.output, "expected a single invocation, parsed %d\n", len(invocations))
+		return nil
+	}
+	return invocations[0]
+}
+
+func (d *Debugger) read(prompt string) (string, string) {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(d.input(prompt)), " ")
+	return strings.ToLower(cmd), strings.TrimSpace(arg)
+}
@@ -0,0 +1,87 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+// returns the lines in order, then empty input
+func scripted(lines ...string) func(string) string {
+	return func(string) string {
+		if len(lines) == 0 {
+			return ""
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line
+	}
+}
+
+func Test_BeforeChat(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDebugger(scripted("p", "c"), &out)
+
+	option := chat.NewChatOption("the system prompt", "prompt", nil)
+	if !d.BeforeChat(1, option) {
+		t.Fatal("run must continue")
+	}
+	if !strings.Contains(out.String(), "the system prompt") {
+		t.Fatalf("system prompt not printed %s", out.String())
+	}
+
+	d = NewDebugger(scripted("q"), &out)
+	if d.BeforeChat(1, option) {
+		t.Fatal("run must be stopped")
+	}
+}
+
+func Test_ContinueSteps(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDebugger(scripted("c 2", "q"), &out)
+	option := chat.NewChatOption("", "", nil)
+
+	payload := "ls"
+	inv := chat.NewInvocation("shell", nil, &payload)
+
+	if !d.BeforeChat(1, option) {
+		t.Fatal("run must continue")
+	}
+	// no pauses during the two steps
+	if _, decision := d.BeforeInvocation(inv); decision != EXECUTE {
+		t.Fatalf("unexpected decision %s", decision)
+	}
+	if !d.BeforeChat(2, option) {
+		t.Fatal("run must continue")
+	}
+	// paused again at the third step
+	if d.BeforeChat(3, option) {
+		t.Fatal("run must be stopped")
+	}
+}
+
+func Test_BeforeInvocation(t *testing.T) {
+	var out bytes.Buffer
+	payload := "ls"
+	inv := chat.NewInvocation("shell", nil, &payload)
+
+	d := NewDebugger(scripted("e", "<shell>id</shell>", "x"), &out)
+	edited, decision := d.BeforeInvocation(inv)
+	if decision != EXECUTE || edited.Action != "shell" || *edited.Payload != "id" {
+		t.Fatalf("unexpected invocation %s %s", edited.FunctionCallString(), decision)
+	}
+
+	// invalid edits keep the original invocation
+	d = NewDebugger(scripted("e", "not xml", "s"), &out)
+	same, decision := d.BeforeInvocation(inv)
+	if decision != SKIP || same != inv {
+		t.Fatalf("unexpected decision %s", decision)
+	}
+
+	d = NewDebugger(scripted("d"), &out)
+	if _, decision := d.BeforeInvocation(inv); decision != DROP {
+		t.Fatalf("unexpected decision %s", decision)
+	}
+}
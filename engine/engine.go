@@ -6,50 +6,221 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/action/agent"
 	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/confirm"
+	"github.com/runetale/notch/engine/debugger"
+	"github.com/runetale/notch/engine/hook"
+	"github.com/runetale/notch/engine/operator"
 	"github.com/runetale/notch/engine/serializer"
+	"github.com/runetale/notch/engine/simulator"
 	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/engine/summarizer"
 	"github.com/runetale/notch/events"
 	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/storage"
 	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
 )
 
 type Engine struct {
 	channel    *events.Channel
 	factory    *llm.LLMFactory
 	state      *state.State
-	maxHistory uint
 	task       *task.Task
 	timeout    *time.Duration
 	nativeTool bool
 	saveTo     string
+	confirmer  confirm.Confirmer
+	hooks      *hook.Chain
+	operator   *operator.Queue
+	summarizer *summarizer.Summarizer
+	// price of the summarizer generator, nil if unknown
+	summaryPricing *llm.Pricing
+	debugger       *debugger.Debugger
+
+	// dry run, nil if actions are executed
+	simulator     simulator.Simulator
+	simulateReads bool
+	// price of the simulator generator, nil if unknown
+	simulatorPricing *llm.Pricing
+	// invocations simulated by this engine and its sub agents
+	plan []string
+
+	// plan and execute mode, nil if disabled
+	planner *planner
+	// critique turns, nil if disabled
+	reflector *reflector
+
+	// parallel safe invocations executed at once
+	concurrency uint
+
+	// repetition and cycle detection over the history
+	loopDetector *state.LoopDetector
+	loopConfig   task.LoopDetection
+	loopCount    int
+
+	waitCh   chan struct{}
+	stopOnce sync.Once
+	result   *Result
+}
+
+func NewEngine(t *task.Task, c *llm.LLMFactory, maxIterations uint, nativeTool bool, saveTo string, confirmer confirm.Confirmer) *Engine {
+	// hooks declared in the task, go hooks are added with Use
+	hooks := hook.NewChain()
+	for _, h := range t.GetHooks() {
+		hooks.Use(hook.NewCommandHook(h, t.GetDir()))
+	}
 
-	waitCh chan struct{}
+	return newEngine(events.NewChannel(), t, c, maxIterations, nativeTool, saveTo, confirmer, hooks, operator.NewQueue())
 }
 
-func NewEngine(t *task.Task, c *llm.LLMFactory, maxIterations uint, nativeTool bool, saveTo string) *Engine {
-	channel := events.NewChannel()
+// the price of a secondary generator, the usage is charged at the main price if it is unknown
+func secondaryPricing(generator *llm.LLMFactory, pricing *llm.Pricing, usage string) *llm.Pricing {
+	if p, found := llm.GetPricing(generator.GetModelName()); found {
+		return &p
+	}
+	log.Printf("Warning: no known price for %s, the %s are charged at the main generator price", generator.GetModelName(), usage)
+	return pricing
+}
 
+// sub agents share the channel, confirmer and hooks of their parent
+func newEngine(channel *events.Channel, t *task.Task, c *llm.LLMFactory, maxIterations uint, nativeTool bool, saveTo string, confirmer confirm.Confirmer, hooks *hook.Chain, queue *operator.Queue) *Engine {
 	serializationInvocationCb := func(inv *chat.Invocation) *string {
 		return serializer.SerializeInvocation(inv)
 	}
-	s := state.NewState(channel, t, maxIterations, serializationInvocationCb)
+	// task prices take precedence over the known model prices
+	var pricing *llm.Pricing
+	budget := t.GetBudget()
+	if budget.InputPrice > 0 || budget.OutputPrice > 0 {
+		pricing = &llm.Pricing{Input: budget.InputPrice, Output: budget.OutputPrice}
+	} else if p, found := llm.GetPricing(c.GetModelName()); found {
+		pricing = &p
+	} else if budget.Cost > 0 {
+		log.Printf("Warning: no known price for %s, the cost budget is not enforced", c.GetModelName())
+	}
+
+	s := state.NewState(channel, t, maxIterations, pricing, serializationInvocationCb)
+
+	loop := t.GetLoopDetection()
 
-	return &Engine{
+	// evicted history is condensed by the main or a secondary generator
+	var sum *summarizer.Summarizer
+	sumPricing := pricing
+	if config := t.GetSummary(); config.Enable {
+		generator := c
+		if config.Generator != "" {
+			secondary, err := c.NewFromGenerator(config.Generator)
+			if err != nil {
+				log.Printf("Warning: summary generator %s, using the main generator", err.Error())
+			} else {
+				generator = secondary
+				sumPricing = secondaryPricing(secondary, pricing, "summaries")
+			}
+		}
+		sum = summarizer.NewSummarizer(generator)
+	}
+
+	// actions with effects outside the engine are simulated
+	var sim simulator.Simulator
+	simPricing := pricing
+	dryRun := t.GetDryRun()
+	if dryRun.Enable {
+		switch simulator.SimulatorType(dryRun.Simulate) {
+		case simulator.STDIN:
+			sim = simulator.NewStdinSimulator(t.GetUserInput)
+		case simulator.MODEL:
+			generator := c
+			if dryRun.Generator != "" {
+				secondary, err := c.NewFromGenerator(dryRun.Generator)
+				if err != nil {
+					log.Printf("Warning: dry run generator %s, using the main generator", err.Error())
+				} else {
+					generator = secondary
+					simPricing = secondaryPricing(secondary, pricing, "simulations")
+				}
+			}
+			sim = simulator.NewModelSimulator(generator, t.GetPrompt())
+		default:
+			sim = simulator.NewCannedSimulator(dryRun.Results)
+		}
+	}
+
+	e := &Engine{
 		channel:    channel,
 		factory:    c,
-		maxHistory: t.GetMaxHistory(),
 		state:      s,
 		task:       t,
 		timeout:    s.GetTask().GetTimeout(),
 		nativeTool: nativeTool,
 		saveTo:     saveTo,
+		confirmer:  confirmer,
+		hooks:      hooks,
+		operator:   queue,
+		summarizer: sum,
+
+		summaryPricing: sumPricing,
+
+		simulator:        sim,
+		simulateReads:    dryRun.SimulateReads,
+		simulatorPricing: simPricing,
+
+		concurrency: t.GetConcurrency(),
+
+		loopDetector: state.NewLoopDetector(loop.Window, loop.MinRepeats, loop.Similarity),
+		loopConfig:   loop,
 
 		waitCh: make(chan struct{}),
 	}
+
+	// the plan is kept in the storage of the planning namespace
+	if config := t.GetPlanExecute(); config.Enable {
+		if s.GetStorage(PLAN_STORAGE) == nil {
+			log.Printf("Warning: plan and execute requires the %s namespace, the mode is disabled", types.PLANNING)
+		} else {
+			e.planner = newPlanner(config)
+		}
+	}
+
+	if config := t.GetReflection(); config.Enable {
+		e.reflector = newReflector(config)
+	}
+
+	// delegate actions spawn sub agents through this engine
+	for _, group := range s.GetNamespaces() {
+		for _, ac := range group.GetActions() {
+			if d, ok := ac.(*agent.Delegate); ok {
+				d.SetSpawner(e.spawn)
+			}
+		}
+	}
+
+	return e
+}
+
+// registers a hook around action execution, must be called before Start
+func (e *Engine) Use(h hook.Hook) {
+	e.hooks.Use(h)
+}
+
+// step mode, pauses before every llm call and every invocation.
+// sub agents are not paused, they run with their own step count
+func (e *Engine) Debug(d *debugger.Debugger) {
+	e.debugger = d
+}
+
+// the invocations a dry run did not execute, in order
+func (e *Engine) DryRunPlan() []string {
+	return e.plan
+}
+
+// operator messages queued here are inserted in the history before the next llm call
+func (e *Engine) Operator() *operator.Queue {
+	return e.operator
 }
 
 func (e *Engine) Start() {
@@ -59,7 +230,16 @@ func (e *Engine) Start() {
 
 func (e *Engine) Stop() {
 	log.Printf("shutdown...")
-	close(e.waitCh)
+	e.finish(STOPPED, "stopped by the operator")
+}
+
+func (e *Engine) stopped() bool {
+	select {
+	case <-e.waitCh:
+		return true
+	default:
+		return false
+	}
 }
 
 func (e *Engine) Done() <-chan struct{} {
@@ -77,99 +257,161 @@ func (e *Engine) consumeEvent() {
 
 func (e *Engine) automaton() {
 	for {
-		// prepare chat option
-		option := e.prepareAutomaton()
+		if e.stopped() {
+			return
+		}
+
+		if max := e.state.GetMaxIteration(); max > 0 && e.state.GetCurrentStep() >= max {
+			e.finish(MAX_STEPS, fmt.Sprintf("reached the maximum of %d steps", max))
+			return
+		}
+
+		if reason, exhausted := e.state.GetBudget().Exhausted(); exhausted {
+			e.finish(BUDGET_EXHAUSTED, reason)
+			return
+		}
+
+		e.step()
+		e.state.IncrementStep()
+	}
+}
+
+// one llm call and the execution of its invocations
+func (e *Engine) step() {
+	// prepare chat option
+	option := e.prepareAutomaton()
+
+	// update state event
+	e.OnUpdateState(option, false)
 
-		// update state event
-		e.OnUpdateState(option, false)
+	// step mode
+	if e.debugger != nil && !e.debugger.BeforeChat(e.state.GetCurrentStep()+1, option) {
+		e.finish(STOPPED, "stopped by the debugger")
+		return
+	}
 
-		// response from llm
-		var invocations []*chat.Invocation
-		toolCalls, response := e.factory.Chat(option, e.nativeTool, e.state.GetNamespaces())
+	// response from llm
+	var invocations []*chat.Invocation
+	toolCalls, response, usage := e.factory.Chat(option, e.nativeTool, e.state.GetNamespaces())
+	e.state.GetBudget().AddUsage(usage)
+
+	// use our strategy
+	var reasoning string
+	if len(toolCalls) == 0 {
+		invocations, reasoning = serializer.TryParseWithReasoning(response)
+	} else {
+		// use native function call by model supports
+		invocations = toolCalls
+		reasoning = serializer.ExtractReasoning(response)
+	}
 
-		// use our strategy
-		if len(toolCalls) == 0 {
-			invocations = serializer.TryParse(response)
+	// return to llm response was null
+	if len(invocations) == 0 {
+		if response == "" {
+			e.onEmptyResponse()
+			return
 		} else {
-			// use native function call by model supports
-			invocations = toolCalls
+			e.onInvalidResponse(response)
+			return
 		}
+	}
 
-		// return to llm response was null
-		if len(invocations) == 0 {
-			if response == "" {
-				e.onEmptyResponse()
-				continue
-			} else {
-				e.onInvalidResponse(response)
-				continue
-			}
-		}
+	// update metrics
+	e.onValidResponse()
 
-		// update metrics
-		e.onValidResponse()
+	// the rationale of the invocations, recorded before they run
+	e.onReasoning(reasoning, invocations)
 
-		// parsing invocations
-		for _, inv := range invocations {
-			// found action
-			ac := e.state.GetAciton(inv.Action)
-			if ac == nil {
-				e.onInvalidAction(inv, nil)
-				break
-			}
+	// parsing and executing invocations
+	from := e.state.GetHistoryLength()
+	failed := e.runInvocations(invocations)
+	e.state.AttachReasoning(from, reasoning)
 
-			// validate actions
-			err := inv.ValidateAction(ac)
-			if err != nil {
-				errStr := err.Error()
-				e.onInvalidAction(inv, &errStr)
-				break
-			}
+	// the model set the task as complete or impossible
+	if e.checkTaskComplete() {
+		return
+	}
 
-			// update metrics
-			e.onValidAction()
+	// check the model is not repeating itself
+	e.checkLoop()
 
-			// timeout
-			timout := e.GetTimeout(ac)
+	// next phase of the plan and execute mode
+	e.advancePlan(failed)
 
-			exec := true
+	// critique of the recent failures before acting again
+	e.checkReflection()
+	if e.stopped() {
+		return
+	}
 
-			// y or n
-			if ac.RequiresUserConfirmation() {
-				log.Println("Warning: user confirmation required")
-				start := time.Now()
-				inp := "nope"
+	// condense the executions evicted from the history window
+	e.summarize()
 
-				for inp != "" && inp != "n" && inp != "y" {
-					log.Println("invocation by y or n")
-					inp = e.task.GetUserInput(fmt.Sprintf("%s [Yn] ", inv.FunctionCallString()))
-					inp = strings.ToLower(inp)
-				}
+	// update state
+	e.OnUpdateState(option, true)
+}
 
-				if inp == "n" {
-					log.Println("Warning: invocation rejected by user")
-					elapsed := time.Since(start)
-					h := fmt.Sprintf("invocation rejected. Elapsed time: %v\n", elapsed)
-					e.onExecutedErrorAction(inv, &h, elapsed)
-					exec = false
-				}
-			}
+func (e *Engine) checkLoop() {
+	if e.loopConfig.Disable {
+		return
+	}
 
-			// exec
-			if exec {
-				start := time.Now()
-				result, err := e.timeoutRun(ac, timout, inv.Attributes, *inv.Payload)
-				if err != nil {
-					e.onTimeoutAction(inv, time.Since(start))
-				}
-				e.onExecutedSuccessAction(inv, &result, time.Since(start))
-			}
+	loop := e.loopDetector.Check(e.state.GetHistory())
+	if loop == nil {
+		e.loopCount = 0
+		return
+	}
+	e.loopCount++
+	e.state.OnEvent(events.NewLoopDetectedEvent(loop.Display(), e.loopCount))
+
+	if e.loopCount < e.loopConfig.Threshold {
+		e.state.AddFeedbackToHistory(fmt.Sprintf(
+			"%s. Repeating actions is prohibited, use the previous results and try something meaningfully different.",
+			loop.Display(),
+		))
+		return
+	}
+
+	switch e.loopConfig.OnThreshold {
+	case task.LOOP_STOP:
+		e.finish(LOOPING, fmt.Sprintf("stuck in a loop, %s", loop.Display()))
+	default:
+		if plan := e.state.GetStorage(PLAN_STORAGE); plan != nil && !plan.IsEmpty() {
+			plan.Clear()
 		}
+		e.state.AddFeedbackToHistory(fmt.Sprintf(
+			"%s. Your current approach is not working and your plan has been cleared. Reflect on the results so far and take a completely different approach towards the goal.",
+			loop.Display(),
+		))
+		e.loopCount = 0
+	}
+}
+
+func (e *Engine) summarize() {
+	if e.summarizer == nil {
+		return
+	}
 
-		// update state
-		e.OnUpdateState(option, true)
-		continue
+	evicted := e.state.GetUnsummarized()
+	if len(evicted) < int(e.task.GetSummary().Batch) {
+		return
 	}
+
+	s := e.state.GetStorage(state.SUMMARY_STORAGE)
+	previous := ""
+	if current, found := s.GetEntry(storage.CURRENT_TAG); found {
+		previous = current.Data
+	}
+
+	summary, usage, err := e.summarizer.Summarize(previous, evicted)
+	e.state.GetBudget().AddPricedUsage(usage, e.summaryPricing)
+	if err != nil {
+		log.Printf("Warning: summary failed %s", err.Error())
+		return
+	}
+
+	s.SetCurrent(summary)
+	e.state.SetSummarized(evicted)
 }
 
 func (e *Engine) timeoutRun(ac action.Action, timeout time.Duration, attributes map[string]string, payload string) (string, error) {
@@ -201,18 +443,30 @@ func (e *Engine) GetTimeout(ac action.Action) time.Duration {
 }
 
 func (e *Engine) prepareAutomaton() *chat.ChatOption {
+	// operator messages typed since the last step
+	for _, message := range e.operator.Drain() {
+		e.onOperatorMessage(message)
+	}
+
+	// phase and current step of the plan and execute mode
+	e.preparePlan()
+
 	e.state.OnEvent(events.NewMetricsEvent(e.state.DisplayMetrics()))
+
+	// get prompt by state
+	return e.chatOption(e.task.GetPrompt())
+}
+
+// the system prompt and the history of the state with the prompt, without changing the state
+func (e *Engine) chatOption(prompt string) *chat.ChatOption {
 	// get system prompt by state
 	systemPrompt, err := serializer.DisplaySystemPrompt(e.state)
 	if err != nil {
 		log.Fatalf("prepare automaton error %s", err.Error())
 	}
 
-	// get prompt by state
-	prompt := e.task.GetPrompt()
-
-	// to chat history, return the history of messsagesb by maxHistory count
-	history := e.state.ToChatHistory(int(e.maxHistory))
+	// to chat history, return the history of messages selected by the task history window
+	history := e.state.ToChatHistory()
 
 	return chat.NewChatOption(systemPrompt, prompt, history)
 }
@@ -227,7 +481,7 @@ func (e *Engine) OnUpdateState(options *chat.ChatOption, refresh bool) {
 		options.UpdateSystemPrompt(sysprompt)
 
 		// update history
-		history := e.state.ToChatHistory(int(e.maxHistory))
+		history := e.state.ToChatHistory()
 		options.UpdateHistroy(history)
 	}
 	histories := make([]string, len(options.GetHistory()))
@@ -237,6 +491,11 @@ func (e *Engine) OnUpdateState(options *chat.ChatOption, refresh bool) {
 	e.state.OnEvent(events.NewStateUpdateEvent(options.GetSystemPrompt(), options.GetPrompt(), strings.Join(histories, "\n"), e.saveTo))
 }
 
+func (e *Engine) onOperatorMessage(message string) {
+	e.state.AddOperatorMessageToHistory(message)
+	e.state.OnEvent(events.NewOperatorMessageEvent(message))
+}
+
 func (e *Engine) onEmptyResponse() {
 	e.state.IncrementEmptyMetrics()
 	e.state.AddUnparsedResponseToHistory("", "return to empty response")
@@ -249,6 +508,23 @@ func (e *Engine) onInvalidResponse(response string) {
 	e.state.OnEvent(events.NewInvalidResponseEvent(response))
 }
 
+func (e *Engine) onReasoning(reasoning string, invocations []*chat.Invocation) {
+	if reasoning == "" {
+		return
+	}
+	serialized := make([]string, 0, len(invocations))
+	for _, inv := range invocations {
+		serialized = append(serialized, *serializer.SerializeInvocation(inv))
+	}
+	event := events.NewReasoningEvent(e.state.GetCurrentStep()+1, reasoning, serialized)
+	if export := e.task.GetReasoning().Export; export != "" {
+		if err := event.Export(export); err != nil {
+			log.Printf("Warning: reasoning export %s", err.Error())
+		}
+	}
+	e.state.OnEvent(event)
+}
+
 func (e *Engine) onValidResponse() {
 	e.state.IncrementValidMetrics()
 }
@@ -257,29 +533,52 @@ func (e *Engine) onValidAction() {
 	e.state.IncrementValidActionsMetrics()
 }
 
-func (e *Engine) onInvalidAction(inv *chat.Invocation, err *string) {
-	e.state.IncrementUnknownMetrics()
-	e.state.AddErrorToHistory(inv, err)
+func (e *Engine) onInvalidAction(inv *chat.Invocation, unknown bool, err *string) {
+	if unknown {
+		e.state.IncrementUnknownMetrics()
+	} else {
+		e.state.IncrementInvalidMetrics()
+	}
+	e.state.AddErrorToHistory(inv, state.ERROR, err)
 	e.state.OnEvent(events.NewInvalidActionEvent(inv.Action, *err))
 }
 
 func (e *Engine) onTimeoutAction(inv *chat.Invocation, start time.Duration) {
 	e.state.IncrementTimeoutActionMetrics()
 	err := "action time out"
-	e.state.AddErrorToHistory(inv, &err)
+	e.state.AddErrorToHistory(inv, state.TIMEOUT, &err)
 	e.state.OnEvent(events.NewActionTimeoutEvent(inv.Action, start))
 }
 
-func (e *Engine) onExecutedErrorAction(inv *chat.Invocation, err *string, start time.Duration) {
-	e.state.IncrementErroredActionMetrics()
-	e.state.AddErrorToHistory(inv, err)
+func (e *Engine) onSkippedAction(inv *chat.Invocation, reason *string) {
+	e.state.IncrementSkippedActionMetrics()
+	e.state.AddErrorToHistory(inv, state.SKIPPED, reason)
+	in := serializer.SerializeInvocation(inv)
+	e.state.OnEvent(events.NewActionSkippedEvent(*in, *reason))
+}
+
+func (e *Engine) onExecutedErrorAction(inv *chat.Invocation, outcome state.Outcome, err *string, start time.Duration) {
+	if outcome == state.REJECTED {
+		e.state.IncrementRejectedActionMetrics()
+	} else {
+		e.state.IncrementErroredActionMetrics()
+	}
+	e.state.AddErrorToHistory(inv, outcome, err)
 	in := serializer.SerializeInvocation(inv)
 	e.state.OnEvent(events.NewActionExecutedEvent(*in, err, nil, start))
 }
 
-func (e *Engine) onExecutedSuccessAction(inv *chat.Invocation, result *string, start time.Duration) {
+func (e *Engine) onSimulatedAction(inv *chat.Invocation, result *string, metadata map[string]string) {
+	e.state.IncrementSimulatedActionMetrics()
+	e.state.AddSimulatedToHistory(inv, result, metadata)
+	in := serializer.SerializeInvocation(inv)
+	e.plan = append(e.plan, *in)
+	e.state.OnEvent(events.NewActionSimulatedEvent(*in, *result))
+}
+
+func (e *Engine) onExecutedSuccessAction(inv *chat.Invocation, result *string, metadata map[string]string, start time.Duration) {
 	e.state.IncrementSuccessActionMetrics()
-	e.state.AddSuccessToHistory(inv, result)
+	e.state.AddSuccessToHistory(inv, result, metadata)
 	in := serializer.SerializeInvocation(inv)
 	e.state.OnEvent(events.NewActionExecutedEvent(*in, nil, result, start))
 }
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/confirm"
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/task"
+)
+
+// a task working on the open ports of a host with the namespaces
+func newTestTask(using ...string) *task.Task {
+	prompt := "find the open ports"
+	tk := &task.Task{Prompt: &prompt}
+	for _, ns := range using {
+		tk.Using = append(tk.Using, &ns)
+	}
+	return tk
+}
+
+// an engine built like the one of a run, the tests do not reach its generator
+func newTestEngine(t *testing.T, tk *task.Task, maxIterations uint) *Engine {
+	t.Helper()
+	options, err := llm.NewLLMOptions("openai://gpt-4@localhost:12321", 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory, err := llm.NewLLMFactory(options, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEngine(tk, factory, maxIterations, false, "", confirm.NewAutoConfirmer(true))
+}
+
+func Test_ReasoningExportOrder(t *testing.T) {
+	export := filepath.Join(t.TempDir(), "reasoning.jsonl")
+	tk := newTestTask("memory")
+	tk.Reasoning = &task.Reasoning{Export: export}
+	e := newTestEngine(t, tk, 0)
+
+	payload := "10.0.0.5"
+	invocations := []*chat.Invocation{chat.NewInvocation("save_memory", nil, &payload)}
+	for i := 0; i < 20; i++ {
+		e.onReasoning(fmt.Sprintf("reasoning %d", i), invocations)
+		e.state.IncrementStep()
+	}
+
+	data, err := os.ReadFile(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 20 {
+		t.Fatalf("expected 20 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		var event events.ReasoningEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Step != uint(i+1) || event.Reasoning != fmt.Sprintf("reasoning %d", i) {
+			t.Fatalf("line %d out of order: %s", i, line)
+		}
+	}
+}
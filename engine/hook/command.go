@@ -0,0 +1,180 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/task"
+)
+
+// written to the command stdin as json
+type CommandInput struct {
+	Stage      Stage             `json:"stage"`
+	Action     string            `json:"action"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Payload    *string           `json:"payload,omitempty"`
+	// only set on the after stage
+	Result   *string           `json:"result,omitempty"`
+	Outcome  string            `json:"outcome,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// read from the command stdout, empty output leaves everything unchanged
+type CommandOutput struct {
+	Veto       bool              `json:"veto"`
+	Reason     string            `json:"reason"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Payload    *string           `json:"payload,omitempty"`
+	Result     *string           `json:"result,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// external command declared in task.yaml hooks
+type CommandHook struct {
+	name    string
+	command string
+	dir     string
+	stage   Stage
+	actions []string
+	timeout time.Duration
+}
+
+func NewCommandHook(h *task.Hook, dir string) Hook {
+	stage := Stage(h.Stage)
+	if stage == "" {
+		stage = BOTH
+	}
+	timeout := 30 * time.Second
+	if h.Timeout > 0 {
+		timeout = time.Duration(h.Timeout) * time.Second
+	}
+	name := h.Name
+	if name == "" {
+		name = h.Command
+	}
+
+	return &CommandHook{
+		name:    name,
+		command: h.Command,
+		dir:     dir,
+		stage:   stage,
+		actions: h.Actions,
+		timeout: timeout,
+	}
+}
+
+func (c *CommandHook) Name() string {
+	return c.name
+}
+
+func (c *CommandHook) Before(inv *chat.Invocation) (*chat.Invocation, error) {
+	if !c.handles(BEFORE, inv) {
+		return inv, nil
+	}
+
+	out, err := c.run(&CommandInput{
+		Stage:      BEFORE,
+		Action:     inv.Action,
+		Attributes: inv.Attributes,
+		Payload:    inv.Payload,
+	})
+	if err != nil {
+		return inv, &VetoError{Hook: c.name, Reason: err.Error()}
+	}
+	if out == nil {
+		return inv, nil
+	}
+	if out.Veto {
+		return inv, &VetoError{Hook: c.name, Reason: out.Reason}
+	}
+
+	attributes := inv.Attributes
+	if out.Attributes != nil {
+		attributes = out.Attributes
+	}
+	payload := inv.Payload
+	if out.Payload != nil {
+		payload = out.Payload
+	}
+	return chat.NewInvocation(inv.Action, attributes, payload), nil
+}
+
+func (c *CommandHook) After(inv *chat.Invocation, result *Result) error {
+	if !c.handles(AFTER, inv) {
+		return nil
+	}
+
+	out, err := c.run(&CommandInput{
+		Stage:      AFTER,
+		Action:     inv.Action,
+		Attributes: inv.Attributes,
+		Payload:    inv.Payload,
+		Result:     &result.Output,
+		Outcome:    result.Outcome,
+		Metadata:   result.Metadata,
+	})
+	if err != nil || out == nil {
+		return err
+	}
+
+	if out.Result != nil {
+		result.Output = *out.Result
+	}
+	for key, value := range out.Metadata {
+		result.SetMetadata(key, value)
+	}
+	return nil
+}
+
+func (c *CommandHook) handles(stage Stage, inv *chat.Invocation) bool {
+	if c.stage != BOTH && c.stage != stage {
+		return false
+	}
+	if len(c.actions) == 0 {
+		return true
+	}
+	for _, name := range c.actions {
+		if name == inv.Action {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CommandHook) run(input *CommandInput) (*CommandOutput, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.command)
+	cmd.Dir = c.dir
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if strings.TrimSpace(stdout.String()) == "" {
+		return nil, nil
+	}
+
+	var out CommandOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("invalid hook output %v", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,125 @@
+// middleware around action execution.
+// hooks run before an invocation is executed, where they can modify or veto it,
+// and after, where they can rewrite the result or attach metadata
+package hook
+
+import (
+	"fmt"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+type Stage string
+
+const (
+	BEFORE Stage = "before"
+	AFTER  Stage = "after"
+	BOTH   Stage = "both"
+)
+
+// output of an executed invocation passed along the after hooks,
+// the error message if the invocation did not succeed
+type Result struct {
+	Output string
+	// success, error, timeout, rejected, skipped or simulated
+	Outcome  string
+	Metadata map[string]string
+}
+
+func (r *Result) SetMetadata(key, value string) {
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]string, 0)
+	}
+	r.Metadata[key] = value
+}
+
+// returned by a before hook to reject the invocation
+type VetoError struct {
+	Hook   string
+	Reason string
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("invocation vetoed by %s: %s", e.Hook, e.Reason)
+}
+
+type Hook interface {
+	Name() string
+	// returns the invocation to execute, or an error to veto it
+	Before(inv *chat.Invocation) (*chat.Invocation, error)
+	// can modify the result in place
+	After(inv *chat.Invocation, result *Result) error
+}
+
+// registers plain functions as a hook, nil functions are skipped
+type Funcs struct {
+	HookName   string
+	BeforeFunc func(inv *chat.Invocation) (*chat.Invocation, error)
+	AfterFunc  func(inv *chat.Invocation, result *Result) error
+}
+
+func (f *Funcs) Name() string {
+	return f.HookName
+}
+
+func (f *Funcs) Before(inv *chat.Invocation) (*chat.Invocation, error) {
+	if f.BeforeFunc == nil {
+		return inv, nil
+	}
+	return f.BeforeFunc(inv)
+}
+
+func (f *Funcs) After(inv *chat.Invocation, result *Result) error {
+	if f.AfterFunc == nil {
+		return nil
+	}
+	return f.AfterFunc(inv, result)
+}
+
+type Chain struct {
+	hooks []Hook
+}
+
+func NewChain(hooks ...Hook) *Chain {
+	return &Chain{
+		hooks: hooks,
+	}
+}
+
+func (c *Chain) Use(h Hook) {
+	c.hooks = append(c.hooks, h)
+}
+
+func (c *Chain) Len() int {
+	return len(c.hooks)
+}
+
+// runs every before hook in registration order, each one receives the
+// invocation returned by the previous. any error vetoes the invocation
+func (c *Chain) Before(inv *chat.Invocation) (*chat.Invocation, error) {
+	for _, h := range c.hooks {
+		next, err := h.Before(inv)
+		if err != nil {
+			if _, ok := err.(*VetoError); ok {
+				return inv, err
+			}
+			return inv, &VetoError{Hook: h.Name(), Reason: err.Error()}
+		}
+		if next != nil {
+			inv = next
+		}
+	}
+	return inv, nil
+}
+
+// runs every after hook in reverse registration order, so the first
+// registered hook sees the final result. errors are collected, not fatal
+func (c *Chain) After(inv *chat.Invocation, result *Result) []error {
+	var errs []error
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		if err := c.hooks[i].After(inv, result); err != nil {
+			errs = append(errs, fmt.Errorf("hook %s: %w", c.hooks[i].Name(), err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,113 @@
+package hook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/task"
+)
+
+func Test_ChainBefore(t *testing.T) {
+	upper := &Funcs{
+		HookName: "upper",
+		BeforeFunc: func(inv *chat.Invocation) (*chat.Invocation, error) {
+			p := strings.ToUpper(*inv.Payload)
+			return chat.NewInvocation(inv.Action, inv.Attributes, &p), nil
+		},
+	}
+	guard := &Funcs{
+		HookName: "guard",
+		BeforeFunc: func(inv *chat.Invocation) (*chat.Invocation, error) {
+			if strings.Contains(*inv.Payload, "RM") {
+				return nil, errors.New("rm is not allowed")
+			}
+			return inv, nil
+		},
+	}
+	chain := NewChain(upper, guard)
+
+	payload := "whoami"
+	inv, err := chain.Before(chat.NewInvocation("shell", nil, &payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *inv.Payload != "WHOAMI" {
+		t.Fatalf("expected modified payload, got %s", *inv.Payload)
+	}
+
+	payload = "rm -rf /"
+	_, err = chain.Before(chat.NewInvocation("shell", nil, &payload))
+	var veto *VetoError
+	if !errors.As(err, &veto) || veto.Hook != "guard" {
+		t.Fatalf("expected veto by guard, got %v", err)
+	}
+}
+
+func Test_ChainAfter(t *testing.T) {
+	order := []string{}
+	first := &Funcs{
+		HookName: "first",
+		AfterFunc: func(inv *chat.Invocation, result *Result) error {
+			order = append(order, "first")
+			return nil
+		},
+	}
+	redact := &Funcs{
+		HookName: "redact",
+		AfterFunc: func(inv *chat.Invocation, result *Result) error {
+			order = append(order, "redact")
+			result.Output = strings.ReplaceAll(result.Output, "hunter2", "[REDACTED]")
+			result.SetMetadata("redacted", "true")
+			return nil
+		},
+	}
+	chain := NewChain(first, redact)
+
+	payload := "cat creds"
+	result := &Result{Output: "password=hunter2"}
+	if errs := chain.After(chat.NewInvocation("shell", nil, &payload), result); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if result.Output != "password=[REDACTED]" || result.Metadata["redacted"] != "true" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if strings.Join(order, ",") != "redact,first" {
+		t.Fatalf("after hooks must run in reverse order, got %v", order)
+	}
+}
+
+func Test_CommandHook(t *testing.T) {
+	veto := NewCommandHook(&task.Hook{
+		Name:    "no-sudo",
+		Command: `grep -q sudo && echo '{"veto":true,"reason":"sudo is not allowed"}' || true`,
+		Stage:   string(BEFORE),
+		Actions: []string{"shell"},
+	}, ".")
+
+	payload := "sudo id"
+	if _, err := veto.Before(chat.NewInvocation("shell", nil, &payload)); err == nil {
+		t.Fatal("expected veto")
+	}
+	// other actions are not handled
+	if _, err := veto.Before(chat.NewInvocation("command", nil, &payload)); err != nil {
+		t.Fatal(err)
+	}
+	payload = "id"
+	if _, err := veto.Before(chat.NewInvocation("shell", nil, &payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	rewrite := NewCommandHook(&task.Hook{
+		Command: `echo '{"result":"rewritten","metadata":{"by":"hook"}}'`,
+		Stage:   string(AFTER),
+	}, ".")
+	result := &Result{Output: "original"}
+	if err := rewrite.After(chat.NewInvocation("shell", nil, &payload), result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "rewritten" || result.Metadata["by"] != "hook" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
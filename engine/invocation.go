@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/debugger"
+	"github.com/runetale/notch/engine/hook"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/task"
+)
+
+// invocation accepted by the engine, executed alone or in a batch
+type job struct {
+	inv *chat.Invocation
+	ac  action.Action
+	// empty until the invocation ends
+	outcome state.Outcome
+	// set if the invocation did not succeed
+	err     *string
+	result  string
+	elapsed time.Duration
+	// unknown action or invalid attributes
+	invalid bool
+	// dry run, the simulator returns the result
+	simulated bool
+	usage     llm.Usage
+}
+
+func (j *job) failed() bool {
+	switch j.outcome {
+	case state.ERROR, state.TIMEOUT, state.REJECTED:
+		return true
+	}
+	return false
+}
+
+func (j *job) fail(outcome state.Outcome, err string) *job {
+	j.outcome = outcome
+	j.err = &err
+	return j
+}
+
+// consecutive parallel safe invocations are executed concurrently,
+// any other invocation waits for the previous ones to finish.
+// results are recorded in the order of the invocations.
+// after a failure the remaining invocations are skipped following the task error policy,
+// failures of concurrent invocations are only seen once their batch is finished.
+// returns true if an invocation failed
+func (e *Engine) runInvocations(invocations []*chat.Invocation) bool {
+	policy := e.task.GetErrorPolicy()
+	failed := map[string]bool{}
+
+	batch := []*job{}
+	flush := func() {
+		e.runBatch(batch)
+		for _, j := range batch {
+			if j.failed() {
+				failed[j.inv.Action] = true
+			}
+		}
+		batch = []*job{}
+	}
+
+	for _, inv := range invocations {
+		ac := e.state.GetAciton(inv.Action)
+
+		// sequential actions see the results of the previous ones
+		if ac == nil || !ac.ParallelSafe() {
+			flush()
+		}
+
+		if skip(policy, failed, inv, ac) {
+			j := &job{inv: inv, ac: ac}
+			batch = append(batch, j.fail(state.SKIPPED, "a previous invocation failed, this one was not executed"))
+			continue
+		}
+
+		// step mode, the operator sees the results of the previous invocations
+		if e.debugger != nil && e.debugger.Stepping() {
+			flush()
+			next, decision := e.debugger.BeforeInvocation(inv)
+			switch decision {
+			case debugger.DROP:
+				continue
+			case debugger.QUIT:
+				e.finish(STOPPED, "stopped by the debugger")
+				return len(failed) > 0
+			case debugger.SKIP:
+				j := &job{inv: inv, ac: ac}
+				batch = append(batch, j.fail(state.SKIPPED, "skipped by the operator"))
+				continue
+			}
+			inv = next
+			ac = e.state.GetAciton(inv.Action)
+		}
+
+		j := e.prepare(inv, ac)
+		if j.failed() {
+			failed[inv.Action] = true
+		}
+		if j.outcome == "" && !j.ac.ParallelSafe() {
+			flush()
+			batch = append(batch, j)
+			flush()
+			continue
+		}
+		batch = append(batch, j)
+	}
+	flush()
+	return len(failed) > 0
+}
+
+// true if the invocation must not be executed after the failed actions
+func skip(policy task.ErrorPolicy, failed map[string]bool, inv *chat.Invocation, ac action.Action) bool {
+	if len(failed) == 0 {
+		return false
+	}
+
+	switch policy {
+	case task.ERROR_CONTINUE:
+		return false
+	case task.ERROR_SKIP_DEPENDENT:
+		return failed[inv.Action] || ac == nil || !ac.ParallelSafe()
+	default:
+		return true
+	}
+}
+
+// validates the invocation, runs the before hooks, the budget check and the user confirmation
+func (e *Engine) prepare(inv *chat.Invocation, ac action.Action) *job {
+	j := &job{inv: inv, ac: ac}
+
+	// found action
+	if ac == nil {
+		j.invalid = true
+		return j.fail(state.ERROR, fmt.Sprintf("unknown action '%s'", inv.Action))
+	}
+
+	// validate actions
+	if err := inv.ValidateAction(ac); err != nil {
+		j.invalid = true
+		return j.fail(state.ERROR, err.Error())
+	}
+
+	// update metrics
+	e.onValidAction()
+
+	// plan and execute mode, nothing acts on the target before the plan is written
+	if !e.planAllows(ac) {
+		return j.fail(state.ERROR, fmt.Sprintf("'%s' is not accepted while planning, write the plan first", ac.Name()))
+	}
+
+	if !e.reflectAllows(ac) {
+		return j.fail(state.ERROR, fmt.Sprintf("'%s' is not accepted in a reflection turn, save a lesson or revise the goal", ac.Name()))
+	}
+
+	// before hooks, can modify or veto the invocation
+	if e.hooks.Len() > 0 {
+		start := time.Now()
+		next, err := e.hooks.Before(inv)
+		if err == nil && next.Action != ac.Name() {
+			if ac = e.state.GetAciton(next.Action); ac == nil {
+				err = fmt.Errorf("hooks changed the action to unknown '%s'", next.Action)
+			}
+		}
+		if err == nil {
+			err = next.ValidateAction(ac)
+		}
+		if err != nil {
+			log.Printf("Warning: %s", err.Error())
+			j.elapsed = time.Since(start)
+			return j.fail(state.ERROR, err.Error())
+		}
+		j.inv = next
+		j.ac = ac
+	}
+
+	// budget of executions per action
+	if e.state.GetBudget().ActionExhausted(j.ac.Name()) {
+		return j.fail(state.ERROR, fmt.Sprintf("the execution budget of '%s' is exhausted, use a different action", j.ac.Name()))
+	}
+
+	// nothing is executed, no confirmation is needed
+	if e.simulates(j.ac) {
+		j.simulated = true
+		e.state.GetBudget().AddAction(j.ac.Name())
+		return j
+	}
+
+	// y or n
+	if j.ac.RequiresUserConfirmation() {
+		log.Println("Warning: user confirmation required")
+		start := time.Now()
+		approved, err := e.confirmer.Confirm(j.inv)
+		if err != nil {
+			log.Printf("Warning: confirmation failed %s", err.Error())
+		}
+
+		if !approved {
+			log.Println("Warning: invocation rejected by user")
+			j.elapsed = time.Since(start)
+			return j.fail(state.REJECTED, fmt.Sprintf("invocation rejected. Elapsed time: %v\n", j.elapsed))
+		}
+	}
+
+	e.state.GetBudget().AddAction(j.ac.Name())
+	return j
+}
+
+// executes the accepted jobs with at most the task concurrency at once,
+// then records every job in order
+func (e *Engine) runBatch(jobs []*job) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	// a concurrency of 0 would block the first job
+	sem := make(chan struct{}, max(e.concurrency, 1))
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		if j.outcome != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(j *job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			e.execute(j)
+		}(j)
+	}
+	wg.Wait()
+
+	for _, j := range jobs {
+		e.record(j)
+	}
+}
+
+// true if the action is not executed by the dry run
+func (e *Engine) simulates(ac action.Action) bool {
+	if e.simulator == nil {
+		return false
+	}
+	switch ac.Effect() {
+	case action.WRITE:
+		return true
+	case action.READ:
+		return e.simulateReads
+	default:
+		return false
+	}
+}
+
+func (e *Engine) execute(j *job) {
+	start := time.Now()
+	if j.simulated {
+		result, usage, err := e.simulator.Simulate(j.inv)
+		if err != nil {
+			log.Printf("Warning: %s", err.Error())
+		}
+		j.elapsed = time.Since(start)
+		j.outcome = state.SIMULATED
+		j.result = result
+		j.usage = usage
+		return
+	}
+
+	result, err := e.timeoutRun(j.ac, e.GetTimeout(j.ac), j.inv.Attributes, *j.inv.Payload)
+	j.elapsed = time.Since(start)
+	if err != nil {
+		j.fail(state.TIMEOUT, "action time out")
+		return
+	}
+	j.outcome = state.SUCCESS
+	j.result = result
+}
+
+func (e *Engine) record(j *job) {
+	// the simulator is charged whatever the hooks do
+	if j.outcome == state.SIMULATED {
+		e.state.GetBudget().AddPricedUsage(j.usage, e.simulatorPricing)
+	}
+
+	// after hooks see every outcome, they can rewrite the result or the error and attach metadata
+	res := &hook.Result{Output: j.result, Outcome: string(j.outcome)}
+	if j.err != nil {
+		res.Output = *j.err
+	}
+	if errs := e.hooks.After(j.inv, res); len(errs) > 0 {
+		for _, err := range errs {
+			log.Printf("Warning: %s", err.Error())
+		}
+		// fail closed, the output may hold what the hook had to remove
+		withheld := fmt.Sprintf("the output of '%s' was withheld because an after hook failed", j.inv.Action)
+		e.onExecutedErrorAction(j.inv, state.ERROR, &withheld, j.elapsed)
+		return
+	}
+
+	switch j.outcome {
+	case state.SUCCESS:
+		e.onExecutedSuccessAction(j.inv, &res.Output, res.Metadata, j.elapsed)
+	case state.SIMULATED:
+		e.onSimulatedAction(j.inv, &res.Output, res.Metadata)
+	case state.TIMEOUT:
+		e.onTimeoutAction(j.inv, j.elapsed)
+	case state.SKIPPED:
+		e.onSkippedAction(j.inv, &res.Output)
+	default:
+		if j.invalid {
+			e.onInvalidAction(j.inv, j.ac == nil, &res.Output)
+			return
+		}
+		e.onExecutedErrorAction(j.inv, j.outcome, &res.Output, j.elapsed)
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/action/memory"
+	"github.com/runetale/notch/engine/action/planning"
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/hook"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
+)
+
+// parallel safe action counting how many of its runs overlap
+type fakeAction struct {
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func (f *fakeAction) Name() string        { return "fake" }
+func (f *fakeAction) Description() string { return "" }
+func (f *fakeAction) Run(storage *storage.Storage, attributes map[string]string, payload string) string {
+	n := f.running.Add(1)
+	defer f.running.Add(-1)
+	for {
+		max := f.maxRunning.Load()
+		if n <= max || f.maxRunning.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	// the first invocations finish last
+	delay, _ := time.ParseDuration(payload)
+	time.Sleep(delay)
+	return payload
+}
+func (f *fakeAction) Timeout() *time.Duration              { return nil }
+func (f *fakeAction) ExamplePayload() *string              { return nil }
+func (f *fakeAction) ExampleAttributes() map[string]string { return nil }
+func (f *fakeAction) RequiredVariables() []*string         { return nil }
+func (f *fakeAction) RequiresUserConfirmation() bool       { return false }
+func (f *fakeAction) ParallelSafe() bool                   { return true }
+func (f *fakeAction) Effect() action.Effect                { return action.READ }
+func (f *fakeAction) GetNamespace() types.NamespaceType    { return types.NamespaceType("fake") }
+func (f *fakeAction) NamespaceDescription() string         { return "" }
+
+func newBatchEngine(t *testing.T, hooks ...hook.Hook) *Engine {
+	tk := newTestTask("memory")
+	tk.Concurrency = 2
+	e := newTestEngine(t, tk, 0)
+	for _, h := range hooks {
+		e.Use(h)
+	}
+	return e
+}
+
+func Test_RunBatch(t *testing.T) {
+	e := newBatchEngine(t)
+
+	ac := &fakeAction{}
+	jobs := []*job{}
+	for i := 6; i > 0; i-- {
+		payload := fmt.Sprintf("%dms", i*10)
+		jobs = append(jobs, &job{inv: chat.NewInvocation(ac.Name(), nil, &payload), ac: ac})
+	}
+	e.runBatch(jobs)
+
+	if max := ac.maxRunning.Load(); max != 2 {
+		t.Fatalf("expected 2 invocations at once, got %d", max)
+	}
+	history := e.state.GetHistory()
+	if len(history) != len(jobs) {
+		t.Fatalf("expected %d results, got %d", len(jobs), len(history))
+	}
+	for i, entry := range history {
+		if entry.Outcome != state.SUCCESS || *entry.Result != *jobs[i].inv.Payload {
+			t.Fatalf("result %d out of order: %s", i, *entry.Result)
+		}
+	}
+	// a task without concurrency still runs the jobs one by one
+	e = newBatchEngine(t)
+	e.concurrency = 0
+	done := make(chan bool)
+	payload := "1ms"
+	pending := []*job{{inv: chat.NewInvocation(ac.Name(), nil, &payload), ac: ac}, {inv: chat.NewInvocation(ac.Name(), nil, &payload), ac: ac}}
+	go func() {
+		e.runBatch(pending)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch is blocked without concurrency")
+	}
+}
+
+func Test_SkipPolicy(t *testing.T) {
+	payload := "x"
+	safe := memory.NewRecallMemory()
+	sequential := planning.NewAddStep()
+
+	cases := []struct {
+		policy task.ErrorPolicy
+		failed map[string]bool
+		ac     action.Action
+		want   bool
+	}{
+		{task.ERROR_STOP, map[string]bool{}, safe, false},
+		{task.ERROR_STOP, map[string]bool{"shell": true}, safe, true},
+		{task.ERROR_CONTINUE, map[string]bool{"shell": true}, sequential, false},
+		{task.ERROR_SKIP_DEPENDENT, map[string]bool{"shell": true}, safe, false},
+		{task.ERROR_SKIP_DEPENDENT, map[string]bool{"shell": true}, sequential, true},
+		{task.ERROR_SKIP_DEPENDENT, map[string]bool{safe.Name(): true}, safe, true},
+		{task.ERROR_SKIP_DEPENDENT, map[string]bool{"shell": true}, nil, true},
+	}
+	for i, c := range cases {
+		name := "unknown"
+		if c.ac != nil {
+			name = c.ac.Name()
+		}
+		inv := chat.NewInvocation(name, nil, &payload)
+		if got := skip(c.policy, c.failed, inv, c.ac); got != c.want {
+			t.Fatalf("case %d: %s after %v with %s, got %v want %v", i, name, c.failed, c.policy, got, c.want)
+		}
+	}
+}
+
+func Test_TaskErrorPolicyDefault(t *testing.T) {
+	tk := &task.Task{}
+	if tk.GetErrorPolicy() != task.ERROR_STOP {
+		t.Fatalf("unexpected default %s", tk.GetErrorPolicy())
+	}
+	tk.ErrorPolicy = "unknown"
+	if tk.GetErrorPolicy() != task.ERROR_STOP {
+		t.Fatalf("unknown policies must fall back to stop, got %s", tk.GetErrorPolicy())
+	}
+}
+
+func Test_RecordAfterHooks(t *testing.T) {
+	redact := &hook.Funcs{HookName: "redact", AfterFunc: func(inv *chat.Invocation, result *hook.Result) error {
+		if strings.Contains(result.Output, "broken") {
+			return errors.New("redaction failed")
+		}
+		result.Output = strings.ReplaceAll(result.Output, "s3cret", "[redacted]")
+		return nil
+	}}
+	e := newBatchEngine(t, redact)
+	payload := "cat .env"
+	inv := chat.NewInvocation("shell", nil, &payload)
+
+	// failed invocations are redacted too
+	failed := (&job{inv: inv}).fail(state.ERROR, "permission denied for key s3cret")
+	e.record(failed)
+	// the output is withheld if the hook fails
+	e.record(&job{inv: inv, outcome: state.SUCCESS, result: "s3cret broken"})
+
+	history := e.state.GetHistory()
+	if *history[0].Error != "permission denied for key [redacted]" {
+		t.Fatalf("error not redacted %q", *history[0].Error)
+	}
+	if history[1].Outcome != state.ERROR || history[1].Result != nil || strings.Contains(*history[1].Error, "s3cret") {
+		t.Fatalf("output recorded after a failed hook %+v", history[1])
+	}
+}
+
+func Test_SimulatedUsagePricing(t *testing.T) {
+	tk := newTestTask("memory")
+	tk.DryRun = &task.DryRun{Enable: true, Simulate: "model", Generator: "openai://gpt-4o-mini@localhost:12321"}
+	e := newTestEngine(t, tk, 0)
+
+	payload := "nmap 10.0.0.5"
+	e.record(&job{inv: chat.NewInvocation("command", nil, &payload), outcome: state.SIMULATED, result: "22/tcp open", simulated: true, usage: llm.Usage{PromptTokens: 1_000_000}})
+
+	// charged at the price of the simulator generator
+	mini, _ := llm.GetPricing("gpt-4o-mini")
+	if cost := e.state.GetBudget().GetCost(); cost != mini.Input {
+		t.Fatalf("expected $%.2f, got $%.2f", mini.Input, cost)
+	}
+}
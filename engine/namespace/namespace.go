@@ -1,27 +1,40 @@
 package namespace
 
 import (
+	"log"
+	"os"
+	"time"
+
 	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/action/agent"
+	"github.com/runetale/notch/engine/action/filesystem"
 	"github.com/runetale/notch/engine/action/goal"
+	"github.com/runetale/notch/engine/action/http"
 	"github.com/runetale/notch/engine/action/memory"
 	"github.com/runetale/notch/engine/action/planning"
+	"github.com/runetale/notch/engine/action/rag"
 	"github.com/runetale/notch/engine/action/shell"
 	"github.com/runetale/notch/engine/action/tasklet"
+	"github.com/runetale/notch/engine/action/timer"
+	"github.com/runetale/notch/storage"
 	"github.com/runetale/notch/task"
 	"github.com/runetale/notch/types"
 )
 
 // managed all namespace actions
 type Namespace struct {
+	nsType      types.NamespaceType
 	name        string
 	description string
 	actions     []action.Action
 	// description of storages, using memory types
 	storageDescriptor []*StorageDescriptor
+	// releases what the actions started, e.g. shell sessions
+	closers []func()
 }
 
-// get namespace by types.Namespacetype
-func NewNamespace(ns types.NamespaceType, functions []*task.Function,
+// get namespace by types.Namespacetype, the task configures the namespaces and defines the functions
+func NewNamespace(ns types.NamespaceType, t *task.Task,
 ) *Namespace {
 	var (
 		name        string
@@ -30,19 +43,34 @@ func NewNamespace(ns types.NamespaceType, functions []*task.Function,
 
 	actions := []action.Action{}
 	descriptors := []*StorageDescriptor{}
+	closers := []func(){}
 
 	switch ns {
 	case types.SHELL:
+		config := t.GetShell()
 		s := shell.NewShell()
+		var predefined map[string]*string
+		if config.Persistent {
+			sessions := shell.NewSessions(config.Program, time.Duration(config.Timeout)*time.Second)
+			closers = append(closers, sessions.Close)
+			s = shell.NewSessionShell(sessions)
+			// the default session starts in the current directory
+			if dir, err := os.Getwd(); err == nil {
+				location := shell.Location(shell.DEFAULT_SESSION, dir)
+				predefined = map[string]*string{shell.DEFAULT_SESSION: &location}
+			}
+		}
 		name = "Shell"
 		description = s.NamespaceDescription()
 		actions = append(actions, s)
-		descriptors = append(descriptors, NewStorageDescriptor("shell", types.UNTAGGED, nil))
+		descriptors = append(descriptors, NewStorageDescriptor("shell", types.UNTAGGED, predefined))
 	case types.TASKLET:
-		t := tasklet.NewTasklet()
+		c := tasklet.NewComplete()
+		i := tasklet.NewImpossible()
 		name = "Task"
-		description = t.NamespaceDescription()
-		actions = append(actions, t)
+		description = c.NamespaceDescription()
+		actions = append(actions, c)
+		actions = append(actions, i)
 		descriptors = append(descriptors, NewStorageDescriptor("tasklet", types.UNTAGGED, nil))
 	case types.GOAL:
 		g := goal.NewGoal()
@@ -57,7 +85,9 @@ func NewNamespace(ns types.NamespaceType, functions []*task.Function,
 		description = sm.NamespaceDescription()
 		actions = append(actions, sm)
 		actions = append(actions, dm)
-		descriptors = append(descriptors, NewStorageDescriptor("memories", types.TAGGED, nil))
+		actions = append(actions, memory.NewRecallMemory())
+		actions = append(actions, memory.NewSearchMemories())
+		descriptors = append(descriptors, NewStorageDescriptor(memory.STORAGE, types.TAGGED, nil))
 	case types.PLANNING:
 		as := planning.NewAddStep()
 		ds := planning.NewDeleteStep()
@@ -71,22 +101,105 @@ func NewNamespace(ns types.NamespaceType, functions []*task.Function,
 		actions = append(actions, c)
 		actions = append(actions, sc)
 		actions = append(actions, sic)
+		actions = append(actions, planning.NewInsertStep())
+		actions = append(actions, planning.NewMoveStep())
+		actions = append(actions, planning.NewRenameStep())
+		actions = append(actions, planning.NewSetStatus())
+		actions = append(actions, planning.NewAddDependency())
+		actions = append(actions, planning.NewRemoveDependency())
 		descriptors = append(descriptors, NewStorageDescriptor("plan", types.COMPLETION, nil))
+	case types.FILESYSTEM:
+		config := t.GetFilesystem()
+		sandbox, err := filesystem.NewSandbox(config.Root, config.MaxRead, config.MaxWrite)
+		if err != nil {
+			log.Fatalf("filesystem root %s", err.Error())
+		}
+		r := filesystem.NewReadFile(sandbox)
+		name = "Filesystem"
+		description = r.NamespaceDescription()
+		actions = append(actions, r)
+		actions = append(actions, filesystem.NewWriteFile(sandbox))
+		actions = append(actions, filesystem.NewAppendFile(sandbox))
+		actions = append(actions, filesystem.NewListDir(sandbox))
+		actions = append(actions, filesystem.NewGlob(sandbox))
+		actions = append(actions, filesystem.NewStat(sandbox))
+		actions = append(actions, filesystem.NewDelete(sandbox))
+	case types.AGENT:
+		d := agent.NewDelegate()
+		name = "Agent"
+		description = d.NamespaceDescription()
+		actions = append(actions, d)
+	case types.TIME:
+		w := timer.NewWait()
+		name = "Time"
+		description = w.NamespaceDescription()
+		actions = append(actions, w)
+		actions = append(actions, timer.NewWaitUntil())
+		// the timer starts with the storage, at the start of the run
+		startedAt := time.Now().Format(time.RFC3339)
+		predefined := map[string]*string{}
+		predefined[storage.STARTED_AT_TAG] = &startedAt
+		descriptors = append(descriptors, NewStorageDescriptor("time", types.TIMER, predefined))
+	case types.RAG:
+		config := t.GetRag()
+		// an interface holding a nil embedder is not nil
+		var embedder rag.Embedder
+		if e := t.GetEmbedder(); e != nil {
+			embedder = e
+		}
+		s := rag.NewSearch(rag.NewIndex(config.Path, config.ChunkSize, embedder, config.Cache), config.TopK)
+		name = "RAG"
+		description = s.NamespaceDescription()
+		actions = append(actions, s)
 	case types.HTTP:
-		predefined := map[string]string{}
-		predefined["User-Agent"] = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
-		predefined["Accept-Encoding"] = "deflate"
-		// TODO: NewHTTP need for some pre header value
-		// ac = tasklet.NewHTTP(predefined)
+		config := t.GetHTTP()
+		client := http.NewClient(config.Allow, config.MaxResponse, time.Duration(config.Timeout)*time.Second, config.Insecure)
+		r := http.NewRequest(client)
+		name = "HTTP"
+		description = r.NamespaceDescription()
+		actions = append(actions, r)
+		actions = append(actions, http.NewSetHeader())
+		actions = append(actions, http.NewDeleteHeader())
+		// headers sent with every request
+		userAgent := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+		encoding := "deflate"
+		predefined := map[string]*string{}
+		predefined["User-Agent"] = &userAgent
+		predefined["Accept-Encoding"] = &encoding
+		descriptors = append(descriptors, NewStorageDescriptor("http_headers", types.TAGGED, predefined))
 	default:
-		panic("not implemented namespaces")
+		// the functions of the task
+		var fn *task.Function
+		for _, f := range t.GetFunctions() {
+			if types.NamespaceType(f.Name) == ns {
+				fn = f
+				break
+			}
+		}
+		if fn == nil {
+			log.Fatalf("unknown namespace %s", ns)
+		}
+		name = fn.Name
+		description = fn.Description
+		for _, ac := range fn.Actions {
+			actions = append(actions, tasklet.NewTasklet(fn, ac, t.GetDir()))
+		}
 	}
 
 	return &Namespace{
+		nsType:            ns,
 		name:              name,
 		description:       description,
 		actions:           actions,
 		storageDescriptor: descriptors,
+		closers:           closers,
+	}
+}
+
+// called when the run or the sub agent using the namespace ends
+func (n *Namespace) Close() {
+	for _, close := range n.closers {
+		close()
 	}
 }
 
@@ -98,6 +211,10 @@ func (n *Namespace) GetStrorageDescriptor() []*StorageDescriptor {
 	return n.storageDescriptor
 }
 
+func (n *Namespace) Type() types.NamespaceType {
+	return n.nsType
+}
+
 func (n *Namespace) Name() string {
 	return n.name
 }
@@ -0,0 +1,100 @@
+// messages typed by the operator while the engine is running
+package operator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// queued messages are inserted in the history before the next llm call
+type Queue struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func NewQueue() *Queue {
+	return &Queue{
+		messages: make([]string, 0),
+	}
+}
+
+func (q *Queue) Push(message string) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, message)
+}
+
+// returns the queued messages in order and empties the queue
+func (q *Queue) Drain() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := q.messages
+	q.messages = make([]string, 0)
+	return messages
+}
+
+// queues every line until the reader is closed
+func (q *Queue) ReadLines(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		q.Push(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// control socket only the current user can write to, every line sent by a client is queued.
+// e.g. echo "skip port 22" | nc -U /tmp/notch.sock
+func (q *Queue) ListenUnix(path string) (io.Closer, error) {
+	// the socket left by a previous run, any other file is kept
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// only the user running notch can send messages, the socket is created without access for the others
+	mask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(mask)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("operator socket error %s", err.Error())
+				}
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := q.ReadLines(conn); err != nil {
+					log.Printf("operator connection error %s", err.Error())
+				}
+			}()
+		}
+	}()
+
+	log.Printf("operator messages on unix socket %s", path)
+	return listener, nil
+}
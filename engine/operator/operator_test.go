@@ -0,0 +1,56 @@
+package operator
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_ListenUnix(t *testing.T) {
+	q := NewQueue()
+	path := filepath.Join(t.TempDir(), "notch.sock")
+	closer, err := q.ListenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Fatalf("the socket is open to other users, mode %o", mode)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("skip port 22\n\nfocus on the web server\n"))
+	conn.Close()
+
+	var messages []string
+	for i := 0; i < 100 && len(messages) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		messages = append(messages, q.Drain()...)
+	}
+	if !slices.Equal(messages, []string{"skip port 22", "focus on the web server"}) {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+
+	// a path given by mistake is not removed
+	notes := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(notes, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.ListenUnix(notes); err == nil {
+		t.Fatal("expected a regular file to be refused")
+	}
+	if data, err := os.ReadFile(notes); err != nil || string(data) != "keep me" {
+		t.Fatalf("the file was changed %q %v", data, err)
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
+)
+
+const PLAN_STORAGE = "plan"
+
+type Phase string
+
+const (
+	// the model writes or revises the plan
+	PLANNING Phase = "planning"
+	// the model works on the first step that can be done
+	EXECUTING Phase = "executing"
+)
+
+// state of the plan and execute mode
+type planner struct {
+	phase Phase
+	// position of the step being executed, such as 2.1
+	step string
+	// consecutive failed turns on the step
+	failures    uint
+	maxFailures uint
+	// reason given to the model for the next planning turn
+	reason string
+}
+
+func newPlanner(config task.PlanExecute) *planner {
+	return &planner{
+		phase:       PLANNING,
+		maxFailures: config.MaxFailures,
+		reason:      "there is no plan yet",
+	}
+}
+
+// sets the phase and the focus of the step, called before the llm call
+func (e *Engine) preparePlan() {
+	if e.planner == nil {
+		return
+	}
+	p := e.planner
+
+	plan := e.state.GetStorage(PLAN_STORAGE)
+	steps := plan.GetSteps()
+	if p.phase == EXECUTING {
+		current := nextStep(steps)
+		switch {
+		case len(steps) == 0:
+			e.replan("the plan is empty")
+		case current == nil && allDone(steps):
+			e.replan("every step of the plan is completed, if the goal is achieved complete the task, otherwise add the missing steps")
+		case current == nil:
+			e.replan("no step can be done, the remaining steps failed, are blocked or wait for them. Revise the plan")
+		default:
+			if current.Path != p.step {
+				p.step = current.Path
+				p.failures = 0
+			}
+			if current.Status == storage.PENDING {
+				plan.SetStepStatus(current.Path, storage.IN_PROGRESS)
+			}
+			e.state.SetFocus(fmt.Sprintf(
+				"## Current step\n\nYou are executing step %s of your plan: %s\n\n"+
+					"Work only on this step. Once its result is verified, mark it as completed with set_step_completed, "+
+					"or set its status to failed or blocked with set_step_status. "+
+					"If the step can not be done, revise the plan.",
+				p.step, current.Data,
+			))
+			return
+		}
+	}
+
+	e.state.SetFocus(fmt.Sprintf(
+		"## Planning\n\n%s. Write the plan with the planning actions before executing anything, "+
+			"one action per step, in the order they must be done. Only planning, memory, goal and task actions are accepted now.",
+		p.reason,
+	))
+}
+
+// moves to the next phase once the turn is recorded
+func (e *Engine) advancePlan(failed bool) {
+	if e.planner == nil {
+		return
+	}
+	p := e.planner
+
+	switch p.phase {
+	case PLANNING:
+		if nextStep(e.state.GetStorage(PLAN_STORAGE).GetSteps()) != nil {
+			p.phase = EXECUTING
+			p.step = ""
+			p.failures = 0
+		}
+	case EXECUTING:
+		if !failed {
+			p.failures = 0
+			return
+		}
+		p.failures++
+		if p.failures >= p.maxFailures {
+			e.replan(fmt.Sprintf("step %s failed %d times in a row, revise the plan: change, split or replace the step, or clear the plan and start over", p.step, p.failures))
+		}
+	}
+}
+
+// the first step without sub steps that is not finished, blocked or waiting for another step
+func nextStep(steps []*storage.Step) *storage.Step {
+	for _, step := range steps {
+		if step.Actionable() {
+			return step
+		}
+	}
+	return nil
+}
+
+func allDone(steps []*storage.Step) bool {
+	for _, step := range steps {
+		if step.Status != storage.DONE {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Engine) replan(reason string) {
+	p := e.planner
+	log.Printf("plan and execute, re-planning: %s", reason)
+	p.phase = PLANNING
+	p.failures = 0
+	p.reason = reason
+}
+
+// during planning only the actions that do not act on the target are accepted
+func (e *Engine) planAllows(ac action.Action) bool {
+	if e.planner == nil || e.planner.phase != PLANNING {
+		return true
+	}
+	return ac.Effect() == action.INTERNAL && ac.GetNamespace() != types.AGENT
+}
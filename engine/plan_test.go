@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/action/memory"
+	"github.com/runetale/notch/engine/action/shell"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/task"
+)
+
+func newPlanEngine(t *testing.T, maxFailures uint) *Engine {
+	tk := newTestTask("planning")
+	tk.Plan = &task.PlanExecute{Enable: true, MaxFailures: maxFailures}
+	e := newTestEngine(t, tk, 0)
+	if e.planner == nil {
+		t.Fatal("plan and execute mode not enabled")
+	}
+	return e
+}
+
+func Test_PlanPhases(t *testing.T) {
+	e := newPlanEngine(t, 2)
+	plan := e.state.GetStorage(PLAN_STORAGE)
+
+	e.preparePlan()
+	if e.planner.phase != PLANNING || !strings.Contains(e.state.GetFocus(), "## Planning") {
+		t.Fatalf("unexpected phase %s focus %s", e.planner.phase, e.state.GetFocus())
+	}
+	if e.planAllows(shell.NewShell()) || !e.planAllows(memory.NewSaveMemroy()) {
+		t.Fatal("only internal actions are accepted while planning")
+	}
+
+	// still planning until the model writes a step
+	e.advancePlan(false)
+	if e.planner.phase != PLANNING {
+		t.Fatalf("unexpected phase %s", e.planner.phase)
+	}
+
+	plan.AddCompletion("scan the host")
+	plan.AddCompletion("report the ports")
+	e.advancePlan(false)
+	e.preparePlan()
+	if e.planner.phase != EXECUTING || e.planner.step != "1" || !strings.Contains(e.state.GetFocus(), "step 1 of your plan: scan the host") {
+		t.Fatalf("unexpected phase %s step %s focus %s", e.planner.phase, e.planner.step, e.state.GetFocus())
+	}
+
+	plan.SetComplete(1)
+	e.preparePlan()
+	if e.planner.step != "2" {
+		t.Fatalf("unexpected step %s", e.planner.step)
+	}
+
+	// re-planning after consecutive failures
+	e.advancePlan(true)
+	if e.planner.phase != EXECUTING {
+		t.Fatalf("unexpected phase %s", e.planner.phase)
+	}
+	e.advancePlan(true)
+	e.preparePlan()
+	if e.planner.phase != PLANNING || !strings.Contains(e.state.GetFocus(), "step 2 failed 2 times") {
+		t.Fatalf("unexpected phase %s focus %s", e.planner.phase, e.state.GetFocus())
+	}
+
+	// every step completed
+	e.advancePlan(false)
+	plan.SetComplete(2)
+	e.preparePlan()
+	if e.planner.phase != PLANNING || !strings.Contains(e.state.GetFocus(), "every step of the plan is completed") {
+		t.Fatalf("unexpected phase %s focus %s", e.planner.phase, e.state.GetFocus())
+	}
+}
+
+func Test_PlanTreeSteps(t *testing.T) {
+	e := newPlanEngine(t, 3)
+	plan := e.state.GetStorage(PLAN_STORAGE)
+	plan.AddCompletion("recon")
+	plan.AddCompletion("exploitation")
+	plan.AddStep("2", "ssh brute force")
+	plan.AddStep("2", "web login")
+	plan.AddDependency("2", "1")
+
+	e.advancePlan(false)
+	e.preparePlan()
+	if e.planner.step != "1" || plan.GetSteps()[0].Status != storage.IN_PROGRESS {
+		t.Fatalf("unexpected step %s", e.planner.step)
+	}
+
+	// sub steps wait for the dependencies of their parent
+	plan.SetComplete(1)
+	plan.SetStepStatus("2.1", storage.BLOCKED)
+	e.preparePlan()
+	if e.planner.step != "2.2" || !strings.Contains(e.state.GetFocus(), "step 2.2 of your plan: web login") {
+		t.Fatalf("unexpected step %s focus %s", e.planner.step, e.state.GetFocus())
+	}
+
+	plan.SetStepStatus("2.2", storage.FAILED)
+	e.preparePlan()
+	if e.planner.phase != PLANNING || !strings.Contains(e.state.GetFocus(), "no step can be done") {
+		t.Fatalf("unexpected phase %s focus %s", e.planner.phase, e.state.GetFocus())
+	}
+}
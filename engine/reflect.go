@@ -0,0 +1,149 @@
+package engine
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/serializer"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/task"
+	"github.com/runetale/notch/types"
+)
+
+//go:embed reflect.prompt
+var reflectPrompt string
+
+// executions shown to the model in a reflection turn
+const reflectExecutions = 10
+
+// critique turns after failures or at a fixed cadence
+type reflector struct {
+	afterFailures uint
+	every         uint
+	prompt        string
+	// steps since the last reflection
+	steps uint
+	// history length at the last reflection, older failures are not counted again
+	last int
+	// set during the reflection turn
+	reflecting bool
+}
+
+func newReflector(config task.Reflection) *reflector {
+	prompt := reflectPrompt
+	if config.Prompt != "" {
+		prompt = config.Prompt
+	}
+	return &reflector{
+		afterFailures: config.AfterFailures,
+		every:         config.Every,
+		prompt:        prompt,
+	}
+}
+
+// runs a reflection turn if the last executions failed or the cadence is reached
+func (e *Engine) checkReflection() {
+	if e.reflector == nil {
+		return
+	}
+	r := e.reflector
+	r.steps++
+
+	history := e.state.GetHistory()
+	var reason string
+	if failures := trailingFailures(history[r.last:]); r.afterFailures > 0 && failures >= r.afterFailures {
+		reason = fmt.Sprintf("the last %d executions failed", failures)
+	} else if r.every > 0 && r.steps >= r.every {
+		reason = fmt.Sprintf("%d steps since the last reflection", r.steps)
+	} else {
+		return
+	}
+
+	e.reflect(reason, history[max(r.last, len(history)-reflectExecutions):])
+	r.steps = 0
+	r.last = len(e.state.GetHistory())
+}
+
+// errored or timed out executions at the end of the history,
+// skipped invocations and messages without outcome are ignored
+func trailingFailures(history []*state.Execution) uint {
+	failures := uint(0)
+	for i := len(history) - 1; i >= 0; i-- {
+		switch history[i].Outcome {
+		case state.ERROR, state.TIMEOUT:
+			failures++
+		case state.SKIPPED, "":
+			continue
+		default:
+			return failures
+		}
+	}
+	return failures
+}
+
+func (e *Engine) reflect(reason string, recent []*state.Execution) {
+	// the run ends with this step, no turn is left to act on the critique
+	if max := e.state.GetMaxIteration(); max > 0 && e.state.GetCurrentStep()+1 >= max {
+		return
+	}
+	if _, exhausted := e.state.GetBudget().Exhausted(); exhausted {
+		return
+	}
+
+	e.state.OnEvent(events.NewReflectionEvent(reason))
+
+	var prompt strings.Builder
+	prompt.WriteString(e.reflector.prompt)
+	prompt.WriteString(fmt.Sprintf("\nReason of this reflection: %s.\n\n# Recent executions\n\n", reason))
+	for _, entry := range recent {
+		prompt.WriteString(serializer.DisplayExecution(entry))
+		prompt.WriteString("\n")
+	}
+
+	// same context as a normal step, with the critique as the prompt.
+	// the operator messages and the plan are left to the next step
+	option := e.chatOption(prompt.String())
+	if e.debugger != nil && !e.debugger.BeforeChat(e.state.GetCurrentStep()+1, option) {
+		e.finish(STOPPED, "stopped by the debugger")
+		return
+	}
+
+	toolCalls, response, usage := e.factory.Chat(option, e.nativeTool, e.state.GetNamespaces())
+	e.state.GetBudget().AddUsage(usage)
+
+	invocations := toolCalls
+	reasoning := serializer.ExtractReasoning(response)
+	if len(invocations) == 0 {
+		invocations, reasoning = serializer.TryParseWithReasoning(response)
+	}
+
+	// a critique without actions is kept as is
+	if len(invocations) == 0 {
+		if response != "" {
+			e.state.AddReflectionToHistory(response)
+		}
+		return
+	}
+
+	e.onReasoning(reasoning, invocations)
+	e.reflector.reflecting = true
+	from := e.state.GetHistoryLength()
+	e.runInvocations(invocations)
+	e.state.AttachReasoning(from, reasoning)
+	e.reflector.reflecting = false
+}
+
+// during a reflection turn only lessons and goal revisions are accepted
+func (e *Engine) reflectAllows(ac action.Action) bool {
+	if e.reflector == nil || !e.reflector.reflecting {
+		return true
+	}
+	switch ac.GetNamespace() {
+	case types.MEMORY, types.GOAL:
+		return true
+	}
+	return false
+}
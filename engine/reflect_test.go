@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"io"
+	"testing"
+
+	"github.com/runetale/notch/engine/action/goal"
+	"github.com/runetale/notch/engine/action/shell"
+	"github.com/runetale/notch/engine/debugger"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/storage"
+	"github.com/runetale/notch/task"
+)
+
+func withOutcomes(outcomes ...state.Outcome) []*state.Execution {
+	history := []*state.Execution{}
+	for _, outcome := range outcomes {
+		entry := state.NewExecution(nil, nil, nil, nil)
+		entry.Outcome = outcome
+		history = append(history, entry)
+	}
+	return history
+}
+
+func Test_TrailingFailures(t *testing.T) {
+	cases := []struct {
+		history []*state.Execution
+		want    uint
+	}{
+		{withOutcomes(), 0},
+		{withOutcomes(state.ERROR, state.SUCCESS), 0},
+		{withOutcomes(state.SUCCESS, state.ERROR, state.TIMEOUT), 2},
+		// skipped invocations and feedback do not break the sequence
+		{withOutcomes(state.ERROR, state.SKIPPED, "", state.ERROR), 2},
+		{withOutcomes(state.ERROR, state.REJECTED, state.TIMEOUT), 1},
+	}
+	for i, c := range cases {
+		if got := trailingFailures(c.history); got != c.want {
+			t.Fatalf("case %d: got %d want %d", i, got, c.want)
+		}
+	}
+}
+
+func Test_ReflectAllows(t *testing.T) {
+	e := &Engine{reflector: newReflector(task.Reflection{Enable: true, AfterFailures: 3})}
+	if !e.reflectAllows(shell.NewShell()) {
+		t.Fatal("every action is accepted outside a reflection turn")
+	}
+
+	e.reflector.reflecting = true
+	if e.reflectAllows(shell.NewShell()) || !e.reflectAllows(goal.NewGoal()) {
+		t.Fatal("only memory and goal actions are accepted in a reflection turn")
+	}
+}
+
+func Test_ReflectWithoutSideEffects(t *testing.T) {
+	tk := newTestTask("planning", "memory")
+	tk.Plan = &task.PlanExecute{Enable: true}
+	tk.Reflection = &task.Reflection{Enable: true, Every: 1}
+
+	// the last step of the run, no reflection
+	e := newTestEngine(t, tk, 1)
+	e.Debug(debugger.NewDebugger(func(string) string { return "q" }, io.Discard))
+	e.reflect("1 steps since the last reflection", nil)
+	if e.Result() != nil {
+		t.Fatal("reflection ran on the last step")
+	}
+
+	e = newTestEngine(t, tk, 10)
+	e.Debug(debugger.NewDebugger(func(string) string { return "q" }, io.Discard))
+	plan := e.state.GetStorage(PLAN_STORAGE)
+	plan.AddCompletion("scan the host")
+	e.Operator().Push("skip port 22")
+
+	e.reflect("1 steps since the last reflection", nil)
+	if result := e.Result(); result == nil || result.Status != STOPPED {
+		t.Fatalf("the reflection did not pause in the debugger %v", result)
+	}
+	// left to the next step
+	if len(e.Operator().Drain()) != 1 {
+		t.Fatal("the reflection consumed the operator messages")
+	}
+	if plan.GetSteps()[0].Status != storage.PENDING {
+		t.Fatalf("the reflection changed the plan %s", plan.GetSteps()[0].Status)
+	}
+}
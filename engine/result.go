@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/runetale/notch/engine/action/tasklet"
+	"github.com/runetale/notch/events"
+)
+
+type Status string
+
+const (
+	COMPLETED  Status = "completed"
+	IMPOSSIBLE Status = "impossible"
+	MAX_STEPS  Status = "max_steps"
+	LOOPING    Status = "looping"
+	STOPPED    Status = "stopped"
+
+	BUDGET_EXHAUSTED Status = "budget_exhausted"
+)
+
+// how the run ended, set once when the engine stops
+type Result struct {
+	Status Status
+	Reason string
+}
+
+func (r *Result) Display() string {
+	return fmt.Sprintf("%s: %s", r.Status, r.Reason)
+}
+
+// nil while the engine is running
+func (e *Engine) Result() *Result {
+	select {
+	case <-e.waitCh:
+		return e.result
+	default:
+		return nil
+	}
+}
+
+// records the result and stops the engine, only the first call has an effect
+func (e *Engine) finish(status Status, reason string) {
+	e.stopOnce.Do(func() {
+		e.result = &Result{Status: status, Reason: reason}
+		switch status {
+		case COMPLETED:
+			e.state.OnEvent(events.NewTaskCompleteEvent(false, &reason))
+		case IMPOSSIBLE:
+			e.state.OnEvent(events.NewTaskCompleteEvent(true, &reason))
+		default:
+			e.state.OnEvent(events.NewStoppedEvent(e.result.Display()))
+		}
+		log.Printf("run finished %s", e.result.Display())
+		e.state.Close()
+		close(e.waitCh)
+	})
+}
+
+// checks the task storage written by the task_complete and task_impossible actions
+func (e *Engine) checkTaskComplete() bool {
+	s := e.state.GetStorage("tasklet")
+	if s == nil {
+		return false
+	}
+	if entry, found := s.GetEntry(tasklet.COMPLETE_TAG); found {
+		e.finish(COMPLETED, entry.Data)
+		return true
+	}
+	if entry, found := s.GetEntry(tasklet.IMPOSSIBLE_TAG); found {
+		e.finish(IMPOSSIBLE, entry.Data)
+		return true
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 type Parsed struct {
 	Processed   int
 	Invocations []*chat.Invocation
+	// text outside of the invocations
+	Text []string
 }
 
 func preprocessBlock(ptr string) string {
@@ -62,6 +65,9 @@ func tryParseBlock(ptr string) Parsed {
 			currElement = &event
 			currPayload.Reset()
 		case xml.CharData:
+			if currElement == nil {
+				parsed.Text = append(parsed.Text, string(event))
+			}
 			currPayload.WriteString(string(event))
 		case xml.EndElement:
 			if currElement != nil {
@@ -135,10 +141,10 @@ func paraseStorage(s *storage.Storage) string {
 
 	switch s.GetStorageType() {
 	case types.TIMER:
-		startedAt := s.GetStartedAt()
-		elapsed := time.Since(startedAt)
-		result = fmt.Sprintf("## Current date: %s\n", time.Now().Format("01 January 2006 15:04"))
-		result += fmt.Sprintf("## Time since start: %v\n", elapsed)
+		result = fmt.Sprintf("## Current date: %s\n", time.Now().Format("02 January 2006 15:04"))
+		if startedAt, found := s.GetStartedAt(); found {
+			result += fmt.Sprintf("## Time since start: %v\n", time.Since(startedAt).Round(time.Second))
+		}
 
 	case types.TAGGED:
 		var xml strings.Builder
@@ -161,12 +167,12 @@ func paraseStorage(s *storage.Storage) string {
 	case types.COMPLETION:
 		var xml strings.Builder
 		xml.WriteString(fmt.Sprintf("<%s>\n", s.GetName()))
-		for _, entry := range s.GetEntries() {
-			status := "not completed"
-			if entry.Complete {
-				status = "COMPLETED"
+		for _, step := range s.GetSteps() {
+			status := string(step.Status)
+			if len(step.Depends) > 0 {
+				status += ", after " + strings.Join(step.Depends, ", ")
 			}
-			xml.WriteString(fmt.Sprintf("  - %s : %s\n", entry.Data, status))
+			xml.WriteString(fmt.Sprintf("%s%s. %s [%s]\n", strings.Repeat("  ", step.Depth+1), step.Path, step.Data, status))
 		}
 		xml.WriteString(fmt.Sprintf("</%s>", s.GetName()))
 		result = xml.String()
@@ -186,3 +192,27 @@ func paraseStorage(s *storage.Storage) string {
 
 	return result
 }
+
+// past the threshold the values of a storage are left out of the prompt
+func keysOnly(s *storage.Storage, after int) bool {
+	return after > 0 && len(s.GetEntryList()) > after
+}
+
+// sorted keys only, the values are read with an action
+func parseStorageKeys(s *storage.Storage) string {
+	entries := s.GetEntryList()
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var xml strings.Builder
+	xml.WriteString(fmt.Sprintf("<%s>\n", s.GetName()))
+	xml.WriteString(fmt.Sprintf("  %d entries, only the keys are shown, recall an entry by its key\n", len(keys)))
+	for _, key := range keys {
+		xml.WriteString(fmt.Sprintf("  - %s\n", key))
+	}
+	xml.WriteString(fmt.Sprintf("</%s>", s.GetName()))
+	return xml.String()
+}
@@ -9,8 +9,10 @@ import (
 	"html/template"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/action/memory"
 	"github.com/runetale/notch/engine/chat"
 	"github.com/runetale/notch/engine/state"
 	"github.com/runetale/notch/storage"
@@ -22,10 +24,15 @@ var actionPrompt string
 //go:embed system.prompt
 var systemPrompt string
 
+// longer outputs of the executions are truncated in the summaries and the reflections
+const maxOutput = 2000
+
 type System struct {
 	SystemPrompt     string
 	Storages         string
 	Iterations       string
+	Budget           string
+	Focus            string
 	AvailableActions string
 	Guidance         string
 }
@@ -53,6 +60,10 @@ func DisplaySystemPrompt(state *state.State) (string, error) {
 	// serialization
 	serializedStorage := []string{}
 	for _, key := range sortedStorageKeys {
+		if key == memory.STORAGE && keysOnly(storages[key], task.GetMemory().KeysOnlyAfter) {
+			serializedStorage = append(serializedStorage, parseStorageKeys(storages[key]))
+			continue
+		}
 		serializedStorage = append(serializedStorage, serializeStorage(storages[key]))
 	}
 	displayStorages := strings.Join(serializedStorage, "\n\n")
@@ -77,6 +88,8 @@ func DisplaySystemPrompt(state *state.State) (string, error) {
 		SystemPrompt:     sysprompt,
 		Storages:         displayStorages,
 		Iterations:       iterations,
+		Budget:           state.GetBudget().Display(),
+		Focus:            state.GetFocus(),
 		AvailableActions: availableActions,
 		Guidance:         guidance,
 	}
@@ -141,6 +154,44 @@ func SerializeInvocation(inv *chat.Invocation) *string {
 	return &invocation
 }
 
+// an execution of the history as plain text, for the summaries and the reflections.
+// longer outputs are truncated
+func DisplayExecution(entry *state.Execution) string {
+	var sb strings.Builder
+	switch {
+	case entry.Operator != nil:
+		sb.WriteString(fmt.Sprintf("[operator] %s\n", *entry.Operator))
+		return sb.String()
+	case entry.Invocation != nil:
+		sb.WriteString(fmt.Sprintf("[action] %s\n", *SerializeInvocation(entry.Invocation)))
+	case entry.Response != nil:
+		sb.WriteString(fmt.Sprintf("[response] %s\n", truncate(*entry.Response)))
+	}
+
+	if entry.Error != nil {
+		outcome := state.ERROR
+		if entry.Outcome != "" {
+			outcome = entry.Outcome
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", outcome, truncate(*entry.Error)))
+	} else if entry.Result != nil {
+		sb.WriteString(fmt.Sprintf("[output] %s\n", truncate(*entry.Result)))
+	}
+	return sb.String()
+}
+
+// keeps at most maxOutput bytes, cut on a rune boundary
+func truncate(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	cut := maxOutput
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n... (%d more bytes)", s[:cut], len(s)-cut)
+}
+
 func SerializeAction(ac action.Action) string {
 	return parseAction(ac)
 }
@@ -150,7 +201,14 @@ func serializeStorage(s *storage.Storage) string {
 }
 
 func TryParse(raw string) []*chat.Invocation {
-	ptr := raw
+	invocations, _ := TryParseWithReasoning(raw)
+	return invocations
+}
+
+// returns the invocations and the text around them,
+// the content of <think> and <thinking> blocks is part of the reasoning
+func TryParseWithReasoning(raw string) ([]*chat.Invocation, string) {
+	reasoning, ptr := extractThinking(raw)
 	var parsedInvocations []*chat.Invocation
 	uniqueMap := make(map[string]bool)
 
@@ -160,6 +218,7 @@ func TryParse(raw string) []*chat.Invocation {
 			break
 		}
 
+		reasoning = append(reasoning, ptr[:openIdx])
 		ptr = ptr[openIdx:]
 
 		parsedBlock := tryParseBlock(ptr)
@@ -167,6 +226,7 @@ func TryParse(raw string) []*chat.Invocation {
 			break
 		}
 
+		reasoning = append(reasoning, parsedBlock.Text...)
 		for _, inv := range parsedBlock.Invocations {
 			uniqueKey := fmt.Sprintf("%s-%v-%v", inv.Action, inv.Attributes, inv.Payload)
 			if !uniqueMap[uniqueKey] {
@@ -177,6 +237,48 @@ func TryParse(raw string) []*chat.Invocation {
 
 		ptr = ptr[parsedBlock.Processed:]
 	}
+	reasoning = append(reasoning, ptr)
+
+	return parsedInvocations, joinReasoning(reasoning)
+}
 
-	return parsedInvocations
+// the reasoning of a response with native tool calls, the whole content
+func ExtractReasoning(raw string) string {
+	reasoning, rest := extractThinking(raw)
+	return joinReasoning(append(reasoning, rest))
+}
+
+var thinkingTags = []string{"think", "thinking"}
+
+// removes the thinking blocks from the response, an unclosed block runs to the end
+func extractThinking(raw string) ([]string, string) {
+	blocks := []string{}
+	for _, tag := range thinkingTags {
+		open, close := fmt.Sprintf("<%s>", tag), fmt.Sprintf("</%s>", tag)
+		for {
+			start := strings.Index(raw, open)
+			if start == -1 {
+				break
+			}
+			end := strings.Index(raw[start:], close)
+			if end == -1 {
+				blocks = append(blocks, raw[start+len(open):])
+				raw = raw[:start]
+				break
+			}
+			blocks = append(blocks, raw[start+len(open):start+end])
+			raw = raw[:start] + raw[start+end+len(close):]
+		}
+	}
+	return blocks, raw
+}
+
+func joinReasoning(parts []string) string {
+	kept := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "\n\n")
 }
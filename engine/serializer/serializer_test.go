@@ -1,10 +1,15 @@
 package serializer
 
 import (
+	"fmt"
 	"log"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/events"
 	"github.com/runetale/notch/storage"
 	"github.com/runetale/notch/types"
 )
@@ -39,3 +44,68 @@ func Test_ParseStorage(t *testing.T) {
 	log.Println("Time Storage Output:")
 	log.Println(paraseStorage(s))
 }
+
+func Test_TryParseWithReasoning(t *testing.T) {
+	raw := "<think>port 22 is open, try the default credentials</think>\nLet me check the banner first.\n<shell>nc 10.0.0.5 22</shell>\ndone"
+	invocations, reasoning := TryParseWithReasoning(raw)
+
+	if len(invocations) != 1 || invocations[0].Action != "shell" || *invocations[0].Payload != "nc 10.0.0.5 22" {
+		t.Fatalf("unexpected invocations %v", invocations)
+	}
+	want := "port 22 is open, try the default credentials\n\nLet me check the banner first.\n\ndone"
+	if reasoning != want {
+		t.Fatalf("unexpected reasoning %q", reasoning)
+	}
+
+	// unclosed thinking runs to the end of the response
+	if reasoning := ExtractReasoning("calling the tool <thinking>still thinking"); reasoning != "still thinking\n\ncalling the tool" {
+		t.Fatalf("unexpected reasoning %q", reasoning)
+	}
+}
+
+func Test_ParseStorageKeys(t *testing.T) {
+	s := storage.NewStorage("memories", types.TAGGED, func(events.DisplayEvent) {})
+	s.AddTagged("web_server", "nginx")
+	s.AddTagged("open_ports", "22, 80")
+
+	if keysOnly(s, 0) || keysOnly(s, 2) || !keysOnly(s, 1) {
+		t.Fatal("unexpected threshold")
+	}
+	want := "<memories>\n  2 entries, only the keys are shown, recall an entry by its key\n  - open_ports\n  - web_server\n</memories>"
+	if got := parseStorageKeys(s); got != want {
+		t.Fatalf("unexpected keys %q", got)
+	}
+}
+
+func Test_ParsePlanStorage(t *testing.T) {
+	s := storage.NewStorage("plan", types.COMPLETION, func(events.DisplayEvent) {})
+	s.AddCompletion("scan")
+	s.AddCompletion("report")
+	s.InsertCompletion(1, "recon")
+	s.SetComplete(1)
+	s.AddStep("2", "tcp ports")
+	s.AddStep("2", "udp ports")
+	s.AddDependency("3", "2")
+
+	want := "<plan>\n  1. recon [done]\n  2. scan [pending]\n    2.1. tcp ports [pending]\n    2.2. udp ports [pending]\n  3. report [pending, after 2]\n</plan>"
+	for i := 0; i < 10; i++ {
+		if got := paraseStorage(s); got != want {
+			t.Fatalf("unexpected plan %q", got)
+		}
+	}
+}
+
+func Test_DisplayExecutionTruncate(t *testing.T) {
+	// two bytes runes, the limit falls in the middle of one
+	result := "a" + strings.Repeat("é", maxOutput)
+	payload := "cat notes.txt"
+	entry := state.NewExecution(nil, chat.NewInvocation("shell", nil, &payload), &result, nil)
+
+	out := DisplayExecution(entry)
+	if !utf8.ValidString(out) {
+		t.Fatal("a rune was split")
+	}
+	if !strings.Contains(out, fmt.Sprintf("\n... (%d more bytes)", len(result)-maxOutput+1)) {
+		t.Fatalf("unexpected output %q", out[len(out)-40:])
+	}
+}
@@ -0,0 +1,98 @@
+// synthetic results of the actions a dry run does not execute
+package simulator
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/serializer"
+	"github.com/runetale/notch/llm"
+)
+
+//go:embed system.prompt
+var systemPrompt string
+
+type SimulatorType string
+
+const (
+	CANNED SimulatorType = "canned"
+	STDIN  SimulatorType = "stdin"
+	MODEL  SimulatorType = "model"
+)
+
+// returned by the canned simulator for actions without a configured result
+const DefaultResult = "dry run, the action was not executed"
+
+// called by the engine instead of running the action
+type Simulator interface {
+	Simulate(inv *chat.Invocation) (string, llm.Usage, error)
+}
+
+// the same configured result for every invocation of an action
+type CannedSimulator struct {
+	results map[string]string
+}
+
+func NewCannedSimulator(results map[string]string) Simulator {
+	return &CannedSimulator{
+		results: results,
+	}
+}
+
+func (s *CannedSimulator) Simulate(inv *chat.Invocation) (string, llm.Usage, error) {
+	if result, ok := s.results[inv.Action]; ok {
+		return result, llm.Usage{}, nil
+	}
+	return DefaultResult, llm.Usage{}, nil
+}
+
+// the operator types the result on the terminal
+type StdinSimulator struct {
+	// concurrent invocations are asked one at a time
+	mu    sync.Mutex
+	input func(prompt string) string
+}
+
+func NewStdinSimulator(input func(prompt string) string) Simulator {
+	return &StdinSimulator{
+		input: input,
+	}
+}
+
+func (s *StdinSimulator) Simulate(inv *chat.Invocation) (string, llm.Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("dry run of %s", inv.FunctionCallString())
+	return s.input("simulated output > "), llm.Usage{}, nil
+}
+
+// the model makes up a plausible result
+type ModelSimulator struct {
+	factory *llm.LLMFactory
+	// the task prompt, describes the target system
+	context string
+}
+
+func NewModelSimulator(factory *llm.LLMFactory, context string) Simulator {
+	return &ModelSimulator{
+		factory: factory,
+		context: context,
+	}
+}
+
+func (s *ModelSimulator) Simulate(inv *chat.Invocation) (string, llm.Usage, error) {
+	prompt := fmt.Sprintf("# Task\n\n%s\n\n# Action\n\n%s\n", s.context, *serializer.SerializeInvocation(inv))
+	option := chat.NewChatOption(systemPrompt, prompt, nil)
+	_, response, usage := s.factory.Chat(option, false, nil)
+
+	result := strings.TrimSpace(response)
+	if result == "" {
+		return DefaultResult, usage, fmt.Errorf("empty simulation of %s", inv.Action)
+	}
+	return result, usage, nil
+}
@@ -0,0 +1,32 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+func Test_CannedSimulator(t *testing.T) {
+	s := NewCannedSimulator(map[string]string{"shell": "uid=0(root)"})
+	payload := "id"
+
+	result, usage, err := s.Simulate(chat.NewInvocation("shell", nil, &payload))
+	if err != nil || result != "uid=0(root)" || usage.Total() != 0 {
+		t.Fatalf("unexpected result %s %v", result, err)
+	}
+
+	result, _, _ = s.Simulate(chat.NewInvocation("http_request", nil, &payload))
+	if result != DefaultResult {
+		t.Fatalf("unexpected default result %s", result)
+	}
+}
+
+func Test_StdinSimulator(t *testing.T) {
+	s := NewStdinSimulator(func(string) string { return "22/tcp open ssh" })
+	payload := "nmap 10.0.0.5"
+
+	result, _, err := s.Simulate(chat.NewInvocation("shell", nil, &payload))
+	if err != nil || result != "22/tcp open ssh" {
+		t.Fatalf("unexpected result %s %v", result, err)
+	}
+}
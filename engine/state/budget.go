@@ -0,0 +1,152 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/task"
+)
+
+// consumption of a run against the task budget, shared with the sub agents of the run
+type Budget struct {
+	mu        sync.Mutex
+	limits    task.Budget
+	pricing   *llm.Pricing
+	startedAt time.Time
+
+	tokens  uint64
+	cost    float64
+	actions map[string]uint
+}
+
+// pricing is nil if the model price is unknown and not set by the task
+func NewBudget(limits task.Budget, pricing *llm.Pricing) *Budget {
+	return &Budget{
+		limits:    limits,
+		pricing:   pricing,
+		startedAt: time.Now(),
+		actions:   make(map[string]uint, 0),
+	}
+}
+
+func (b *Budget) AddUsage(usage llm.Usage) {
+	b.AddPricedUsage(usage, b.pricing)
+}
+
+// usage of another generator than the main one, priced at its own rates.
+// the cost is not counted if the price is nil
+func (b *Budget) AddPricedUsage(usage llm.Usage, pricing *llm.Pricing) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += usage.Total()
+	if pricing != nil {
+		b.cost += pricing.Cost(usage)
+	}
+}
+
+func (b *Budget) AddAction(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.actions[name]++
+}
+
+func (b *Budget) GetTokens() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tokens
+}
+
+func (b *Budget) GetCost() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.cost
+}
+
+// true if the action reached its maximum number of executions
+func (b *Budget) ActionExhausted(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	max, found := b.limits.Actions[name]
+	return found && b.actions[name] >= max
+}
+
+// returns the reason if the time, token or cost budget ran out
+func (b *Budget) Exhausted() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limits.Time > 0 && time.Since(b.startedAt) >= b.limits.Time {
+		return fmt.Sprintf("time budget of %s exhausted", b.limits.Time.String()), true
+	}
+	if b.limits.Tokens > 0 && b.tokens >= b.limits.Tokens {
+		return fmt.Sprintf("token budget of %d exhausted", b.limits.Tokens), true
+	}
+	if b.limits.Cost > 0 && b.pricing != nil && b.cost >= b.limits.Cost {
+		return fmt.Sprintf("cost budget of $%.2f exhausted", b.limits.Cost), true
+	}
+	return "", false
+}
+
+// warnings for the budgets past the warn_at fraction, shown to the model
+func (b *Budget) Warnings() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	warnings := []string{}
+	near := func(used, max float64) bool {
+		return max > 0 && used/max >= b.limits.WarnAt
+	}
+
+	if elapsed := time.Since(b.startedAt); near(float64(elapsed), float64(b.limits.Time)) {
+		remaining := (b.limits.Time - elapsed).Round(time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		warnings = append(warnings, fmt.Sprintf("only %s of the time budget is left", remaining.String()))
+	}
+	if near(float64(b.tokens), float64(b.limits.Tokens)) {
+		warnings = append(warnings, fmt.Sprintf("%d of %d tokens have been used", b.tokens, b.limits.Tokens))
+	}
+	if b.pricing != nil && near(b.cost, b.limits.Cost) {
+		warnings = append(warnings, fmt.Sprintf("$%.2f of the $%.2f cost budget has been spent", b.cost, b.limits.Cost))
+	}
+
+	names := make([]string, 0, len(b.limits.Actions))
+	for name := range b.limits.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		max := b.limits.Actions[name]
+		if b.actions[name] >= max {
+			warnings = append(warnings, fmt.Sprintf("%s cannot be used anymore, its %d executions are exhausted", name, max))
+		} else if near(float64(b.actions[name]), float64(max)) {
+			warnings = append(warnings, fmt.Sprintf("%s can only be executed %d more times", name, max-b.actions[name]))
+		}
+	}
+
+	return warnings
+}
+
+func (b *Budget) Display() string {
+	warnings := b.Warnings()
+	if len(warnings) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Budget\n\n")
+	sb.WriteString("You are running out of budget, prioritize the actions that complete the goal:\n")
+	for _, w := range warnings {
+		sb.WriteString(fmt.Sprintf("- %s\n", w))
+	}
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runetale/notch/llm"
+	"github.com/runetale/notch/task"
+)
+
+func Test_BudgetTokensAndCost(t *testing.T) {
+	b := NewBudget(task.Budget{Tokens: 1000, Cost: 1, WarnAt: 0.8}, &llm.Pricing{Input: 1000, Output: 1000})
+
+	b.AddUsage(llm.Usage{PromptTokens: 300, CompletionTokens: 100})
+	if _, exhausted := b.Exhausted(); exhausted {
+		t.Fatal("budget should not be exhausted")
+	}
+	if len(b.Warnings()) != 0 {
+		t.Fatalf("unexpected warnings %v", b.Warnings())
+	}
+
+	b.AddUsage(llm.Usage{PromptTokens: 400, CompletionTokens: 100})
+	if warnings := b.Warnings(); len(warnings) != 2 {
+		t.Fatalf("expected token and cost warnings, got %v", warnings)
+	}
+
+	b.AddUsage(llm.Usage{PromptTokens: 100})
+	reason, exhausted := b.Exhausted()
+	if !exhausted || !strings.Contains(reason, "token") {
+		t.Fatalf("expected token budget exhausted, got %s", reason)
+	}
+}
+
+func Test_BudgetActions(t *testing.T) {
+	b := NewBudget(task.Budget{Actions: map[string]uint{"shell": 2}, WarnAt: 0.5}, nil)
+
+	b.AddAction("shell")
+	if b.ActionExhausted("shell") || b.ActionExhausted("save_memory") {
+		t.Fatal("action budget should not be exhausted")
+	}
+	if !strings.Contains(b.Display(), "shell can only be executed 1 more times") {
+		t.Fatalf("unexpected display %s", b.Display())
+	}
+
+	b.AddAction("shell")
+	if !b.ActionExhausted("shell") {
+		t.Fatal("action budget should be exhausted")
+	}
+	// action budgets are enforced per invocation, they don't stop the run
+	if _, exhausted := b.Exhausted(); exhausted {
+		t.Fatal("run budget should not be exhausted")
+	}
+}
+
+func Test_BudgetTime(t *testing.T) {
+	b := NewBudget(task.Budget{Time: time.Millisecond, WarnAt: 0.8}, nil)
+	time.Sleep(2 * time.Millisecond)
+	if _, exhausted := b.Exhausted(); !exhausted {
+		t.Fatal("time budget should be exhausted")
+	}
+}
+
+func Test_BudgetPricedUsage(t *testing.T) {
+	b := NewBudget(task.Budget{Cost: 1}, &llm.Pricing{Input: 10, Output: 30})
+
+	b.AddUsage(llm.Usage{PromptTokens: 1_000_000})
+	// a cheaper summarizer
+	b.AddPricedUsage(llm.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}, &llm.Pricing{Input: 0.1, Output: 0.4})
+	if cost := b.GetCost(); cost < 10.49 || cost > 10.51 {
+		t.Fatalf("expected the secondary usage at its own price, got $%.2f", cost)
+	}
+	if b.GetTokens() != 3_000_000 {
+		t.Fatalf("unexpected tokens %d", b.GetTokens())
+	}
+
+	// an unknown price only counts the tokens
+	b.AddPricedUsage(llm.Usage{PromptTokens: 1_000_000}, nil)
+	if cost := b.GetCost(); cost > 10.51 || b.GetTokens() != 4_000_000 {
+		t.Fatalf("unexpected cost $%.2f", cost)
+	}
+}
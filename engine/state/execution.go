@@ -3,6 +3,19 @@ package state
 
 import "github.com/runetale/notch/engine/chat"
 
+// how an invocation ended
+type Outcome string
+
+const (
+	SUCCESS  Outcome = "success"
+	ERROR    Outcome = "error"
+	TIMEOUT  Outcome = "timeout"
+	REJECTED Outcome = "rejected"
+	SKIPPED  Outcome = "skipped"
+	// not executed by a dry run, the result is synthetic
+	SIMULATED Outcome = "simulated"
+)
+
 type Execution struct {
 	// llm response
 	Response *string
@@ -12,6 +25,14 @@ type Execution struct {
 	Result *string
 	// if engine executed error
 	Error *string
+	// empty for entries without invocation, e.g. feedback from the engine
+	Outcome Outcome
+	// attached by hooks after execution
+	Metadata map[string]string
+	// message from the operator during the run
+	Operator *string
+	// text of the response around the invocations, shared by the invocations of a response
+	Reasoning *string
 }
 
 func NewExecution(
@@ -0,0 +1,190 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/runetale/notch/engine/chat"
+)
+
+type LoopKind string
+
+const (
+	REPEAT LoopKind = "repeat"
+	CYCLE  LoopKind = "cycle"
+)
+
+// found by LoopDetector in the latest executions
+type Loop struct {
+	Kind LoopKind
+	// repeated invocations, one for repeat and one per period for cycle
+	Invocations []*chat.Invocation
+	// how many times the invocation or the cycle was seen in the window
+	Count int
+}
+
+func (l *Loop) Display() string {
+	calls := make([]string, len(l.Invocations))
+	for i, inv := range l.Invocations {
+		calls[i] = inv.FunctionCallString()
+	}
+	switch l.Kind {
+	case CYCLE:
+		return fmt.Sprintf("the sequence %s was repeated %d times", strings.Join(calls, " -> "), l.Count)
+	default:
+		return fmt.Sprintf("%s was repeated %d times", strings.Join(calls, ""), l.Count)
+	}
+}
+
+type LoopDetector struct {
+	// number of latest invocations inspected
+	window int
+	// occurrences of the same invocation needed to report a repeat
+	minRepeats int
+	// 0 to 1, two payloads at or above this ratio are considered the same
+	similarity float64
+}
+
+func NewLoopDetector(window, minRepeats int, similarity float64) *LoopDetector {
+	return &LoopDetector{
+		window:     window,
+		minRepeats: minRepeats,
+		similarity: similarity,
+	}
+}
+
+// checks the latest invocations in history,
+// returns nil if the model is not looping
+func (d *LoopDetector) Check(history []*Execution) *Loop {
+	invocations := []*chat.Invocation{}
+	for i := len(history) - 1; i >= 0 && len(invocations) < d.window; i-- {
+		if history[i].Invocation != nil {
+			invocations = append(invocations, history[i].Invocation)
+		}
+	}
+	if len(invocations) < 2 {
+		return nil
+	}
+	// oldest first
+	for i, j := 0, len(invocations)-1; i < j; i, j = i+1, j-1 {
+		invocations[i], invocations[j] = invocations[j], invocations[i]
+	}
+
+	if loop := d.checkRepeat(invocations); loop != nil {
+		return loop
+	}
+	return d.checkCycle(invocations)
+}
+
+// the latest invocation is seen at least minRepeats times in the window
+func (d *LoopDetector) checkRepeat(invocations []*chat.Invocation) *Loop {
+	latest := invocations[len(invocations)-1]
+	count := 0
+	for _, inv := range invocations {
+		if d.same(inv, latest) {
+			count++
+		}
+	}
+	if count >= d.minRepeats {
+		return &Loop{Kind: REPEAT, Invocations: []*chat.Invocation{latest}, Count: count}
+	}
+	return nil
+}
+
+// the tail of the window is a sequence repeated at least twice, e.g. A-B-A-B
+func (d *LoopDetector) checkCycle(invocations []*chat.Invocation) *Loop {
+	n := len(invocations)
+	for period := 2; period*2 <= n; period++ {
+		count := 1
+		for start := n - period*2; start >= 0; start -= period {
+			if !d.sameSequence(invocations[start:start+period], invocations[n-period:]) {
+				break
+			}
+			count++
+		}
+		if count < 2 {
+			continue
+		}
+
+		// a cycle of the same invocation is a repeat, not a cycle
+		cycle := invocations[n-period:]
+		distinct := false
+		for _, inv := range cycle[1:] {
+			if !d.same(inv, cycle[0]) {
+				distinct = true
+				break
+			}
+		}
+		if distinct {
+			return &Loop{Kind: CYCLE, Invocations: cycle, Count: count}
+		}
+	}
+	return nil
+}
+
+func (d *LoopDetector) sameSequence(a, b []*chat.Invocation) bool {
+	for i := range a {
+		if !d.same(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *LoopDetector) same(a, b *chat.Invocation) bool {
+	if a.Action != b.Action {
+		return false
+	}
+	return similarity(normalizeInvocation(a), normalizeInvocation(b)) >= d.similarity
+}
+
+// lower cased payload and sorted attributes with collapsed whitespace
+func normalizeInvocation(inv *chat.Invocation) string {
+	parts := []string{}
+	if inv.Payload != nil {
+		parts = append(parts, strings.Join(strings.Fields(*inv.Payload), " "))
+	}
+
+	keys := make([]string, 0, len(inv.Attributes))
+	for key := range inv.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, strings.Join(strings.Fields(inv.Attributes[key]), " ")))
+	}
+
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+// 1 - levenshtein distance / length of the longer string
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
@@ -0,0 +1,96 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/task"
+)
+
+func execution(action, payload string) *Execution {
+	result := "ok"
+	return NewExecution(nil, chat.NewInvocation(action, nil, &payload), &result, nil)
+}
+
+func Test_LoopDetectorRepeat(t *testing.T) {
+	d := NewLoopDetector(8, 3, 0.9)
+	history := []*Execution{
+		execution("shell", "whoami"),
+		execution("shell", "id"),
+		execution("shell", "whoami "),
+	}
+	if loop := d.Check(history); loop != nil {
+		t.Fatalf("unexpected loop %s", loop.Display())
+	}
+
+	history = append(history, execution("shell", "WHOAMI"))
+	loop := d.Check(history)
+	if loop == nil || loop.Kind != REPEAT || loop.Count != 3 {
+		t.Fatalf("expected a repeat of 3, got %+v", loop)
+	}
+}
+
+func Test_LoopDetectorSweep(t *testing.T) {
+	loop := (&task.Task{}).GetLoopDetection()
+	d := NewLoopDetector(loop.Window, loop.MinRepeats, loop.Similarity)
+	history := []*Execution{}
+	for i := 1; i <= 8; i++ {
+		history = append(history, execution("shell", fmt.Sprintf("nmap -sV 10.0.0.%d", i)))
+		if loop := d.Check(history); loop != nil {
+			t.Fatalf("a sweep over hosts is not a loop, got %s", loop.Display())
+		}
+	}
+
+	// the same host scanned again is
+	for i := 0; i < 2; i++ {
+		history = append(history, execution("shell", "nmap -sV  10.0.0.8"))
+	}
+	if loop := d.Check(history); loop == nil || loop.Kind != REPEAT {
+		t.Fatalf("expected a repeat, got %+v", loop)
+	}
+}
+
+func Test_LoopDetectorCycle(t *testing.T) {
+	d := NewLoopDetector(8, 3, 0.9)
+	history := []*Execution{
+		execution("shell", "ls /tmp"),
+		execution("shell", "cat /tmp/a"),
+		execution("shell", "ls /tmp"),
+	}
+	if loop := d.Check(history); loop != nil {
+		t.Fatalf("unexpected loop %s", loop.Display())
+	}
+
+	history = append(history, execution("shell", "cat /tmp/a"))
+	loop := d.Check(history)
+	if loop == nil || loop.Kind != CYCLE || len(loop.Invocations) != 2 || loop.Count != 2 {
+		t.Fatalf("expected an A-B cycle, got %+v", loop)
+	}
+}
+
+func Test_LoopDetectorWindow(t *testing.T) {
+	d := NewLoopDetector(3, 3, 0.9)
+	history := []*Execution{
+		execution("shell", "whoami"),
+		execution("shell", "whoami"),
+		execution("shell", "uname -a"),
+		execution("shell", "whoami"),
+	}
+	// only one whoami is left out of the window
+	if loop := d.Check(history); loop != nil {
+		t.Fatalf("unexpected loop %s", loop.Display())
+	}
+}
+
+func Test_Similarity(t *testing.T) {
+	if similarity("nmap -sV 10.0.0.1", "nmap -sV 10.0.0.1") != 1 {
+		t.Fatal("identical strings must be similar")
+	}
+	if s := similarity("nmap -sV 10.0.0.1", "nmap -sV 10.0.0.2"); s < 0.9 {
+		t.Fatalf("near identical strings must be similar, got %f", s)
+	}
+	if s := similarity("whoami", "cat /etc/passwd"); s > 0.5 {
+		t.Fatalf("different strings must not be similar, got %f", s)
+	}
+}
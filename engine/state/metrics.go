@@ -13,6 +13,8 @@ type ErrorMetrics struct {
 	invalidActions    uint
 	erroredActions    uint
 	timedoutActions   uint
+	rejectedActions   uint
+	skippedActions    uint
 }
 
 func (e ErrorMetrics) HasResponseErrors() bool {
@@ -20,7 +22,8 @@ func (e ErrorMetrics) HasResponseErrors() bool {
 }
 
 func (e ErrorMetrics) HasActionErrors() bool {
-	return e.erroredActions > 0 || e.unknownActions > 0 || e.invalidActions > 0
+	return e.erroredActions > 0 || e.unknownActions > 0 || e.invalidActions > 0 ||
+		e.timedoutActions > 0 || e.rejectedActions > 0 || e.skippedActions > 0
 }
 
 func MemoryStats() uint64 {
@@ -41,12 +44,14 @@ func HumanBytes(bytes uint64) string {
 }
 
 type Metrics struct {
-	maxStep        uint
-	currentStep    uint
-	validResponses uint
-	validActions   uint
-	successActions uint
-	errors         ErrorMetrics
+	maxStep          uint
+	currentStep      uint
+	validResponses   uint
+	validActions     uint
+	successActions   uint
+	simulatedActions uint
+	subAgents        uint
+	errors           ErrorMetrics
 }
 
 func NewMetrics(maxStep uint) *Metrics {
@@ -76,19 +81,47 @@ func (m *Metrics) Display() string {
 
 	if m.errors.HasActionErrors() {
 		sb.WriteString(fmt.Sprintf(
-			"actions(valid:%d ok:%d errored:%d unknown:%d invalid:%d) ",
+			"actions(valid:%d ok:%d errored:%d unknown:%d invalid:%d timedout:%d rejected:%d skipped:%d) ",
 			m.validActions,
 			m.successActions,
 			m.errors.erroredActions,
 			m.errors.unknownActions,
 			m.errors.invalidActions,
+			m.errors.timedoutActions,
+			m.errors.rejectedActions,
+			m.errors.skippedActions,
 		))
 	} else if m.validActions > 0 {
 		sb.WriteString(fmt.Sprintf("actions:%d ", m.validActions))
 	}
 
+	if m.simulatedActions > 0 {
+		sb.WriteString(fmt.Sprintf("simulated:%d ", m.simulatedActions))
+	}
+
+	if m.subAgents > 0 {
+		sb.WriteString(fmt.Sprintf("agents:%d ", m.subAgents))
+	}
+
 	memUsage := MemoryStats()
 	sb.WriteString(fmt.Sprintf("mem:%s", HumanBytes(memUsage)))
 
 	return sb.String()
 }
+
+// rolls up the metrics of a finished sub agent, steps are counted per engine
+func (m *Metrics) Add(child *Metrics) {
+	m.subAgents += 1 + child.subAgents
+	m.validResponses += child.validResponses
+	m.validActions += child.validActions
+	m.successActions += child.successActions
+	m.simulatedActions += child.simulatedActions
+	m.errors.emptyResponses += child.errors.emptyResponses
+	m.errors.unparsedResponses += child.errors.unparsedResponses
+	m.errors.unknownActions += child.errors.unknownActions
+	m.errors.invalidActions += child.errors.invalidActions
+	m.errors.erroredActions += child.errors.erroredActions
+	m.errors.timedoutActions += child.errors.timedoutActions
+	m.errors.rejectedActions += child.errors.rejectedActions
+	m.errors.skippedActions += child.errors.skippedActions
+}
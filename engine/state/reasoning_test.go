@@ -0,0 +1,66 @@
+package state
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/task"
+)
+
+func newReasoningState(policy task.ReasoningPolicy) *State {
+	prompt := "find the open ports"
+	using := "memory"
+	tk := &task.Task{Prompt: &prompt, Using: []*string{&using}, Reasoning: &task.Reasoning{History: policy}}
+	cb := func(inv *chat.Invocation) *string {
+		s := inv.FunctionCallString()
+		return &s
+	}
+	return NewState(events.NewChannel(), tk, 0, nil, cb)
+}
+
+// two responses, the first one with two invocations
+func addResponses(s *State) {
+	for i, reasoning := range []string{"first reasoning", "second reasoning"} {
+		from := s.GetHistoryLength()
+		for j := 0; j <= 1-i; j++ {
+			payload, result := "ls", "ok"
+			s.AddSuccessToHistory(chat.NewInvocation("shell", nil, &payload), &result, nil)
+		}
+		s.AttachReasoning(from, reasoning)
+	}
+}
+
+func agentMessages(s *State) string {
+	parts := []string{}
+	for _, m := range s.ToChatHistory() {
+		if m.MessageType == chat.AGETNT {
+			parts = append(parts, *m.Response)
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+func Test_ReasoningHistory(t *testing.T) {
+	s := newReasoningState(task.REASONING_NONE)
+	addResponses(s)
+	if strings.Contains(agentMessages(s), "reasoning") {
+		t.Fatalf("reasoning must not be sent back %s", agentMessages(s))
+	}
+	if s.GetHistory()[1].Reasoning == nil || *s.GetHistory()[1].Reasoning != "first reasoning" {
+		t.Fatal("reasoning not attached to every invocation of the response")
+	}
+
+	s = newReasoningState(task.REASONING_LAST)
+	addResponses(s)
+	if got := agentMessages(s); strings.Contains(got, "first reasoning") || strings.Count(got, "second reasoning") != 1 {
+		t.Fatalf("only the latest reasoning must be sent back %s", got)
+	}
+
+	s = newReasoningState(task.REASONING_ALL)
+	addResponses(s)
+	if got := agentMessages(s); strings.Count(got, "first reasoning") != 1 || strings.Count(got, "second reasoning") != 1 {
+		t.Fatalf("each reasoning must be sent back once %s", got)
+	}
+}
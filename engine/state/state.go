@@ -4,16 +4,21 @@ package state
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/runetale/notch/engine/action"
+	"github.com/runetale/notch/engine/action/memory"
 	"github.com/runetale/notch/engine/chat"
 	"github.com/runetale/notch/engine/namespace"
 	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/llm"
 	"github.com/runetale/notch/storage"
 	"github.com/runetale/notch/task"
 	"github.com/runetale/notch/types"
 )
 
+const SUMMARY_STORAGE = "summary"
+
 type State struct {
 	task       *task.Task
 	storages   map[string]*storage.Storage
@@ -31,6 +36,17 @@ type State struct {
 	SerializeInvocation func(inv *chat.Invocation) *string
 
 	metrics *Metrics
+	budget  *Budget
+	window  *Window
+
+	// evicted executions already condensed into the summary storage
+	summarized map[*Execution]bool
+
+	// what the model must work on this step, set by the plan and execute mode
+	focus string
+
+	// reasoning sent back to the model
+	reasoning task.ReasoningPolicy
 }
 
 // TODO implement rag model
@@ -38,6 +54,7 @@ func NewState(
 	sender *events.Channel,
 	task *task.Task,
 	maxIterations uint,
+	pricing *llm.Pricing,
 	serializationInvocation func(inv *chat.Invocation) *string,
 ) *State {
 	namespaces := make([]*namespace.Namespace, 0)
@@ -52,21 +69,32 @@ func NewState(
 
 	// get namespaces
 	using := task.GetUsing()
+	enabled := []types.NamespaceType{}
 	if len(using) == 0 {
 		// creating default namespaces
-		ns := types.GetNameSpaceValues()
-		for _, o := range ns {
-			namespaces = append(namespaces, namespace.NewNamespace(o, nil))
-		}
+		enabled = types.GetDefaultNameSpaceValues()
 	} else {
-		// adding only task defined namespaces
+		// adding only task defined namespaces, '*' is every namespace
 		for _, o := range using {
 			if *o == "*" {
-				*o = ""
+				enabled = append(enabled, types.GetNameSpaceValues()...)
 				continue
 			}
-			namespaces = append(namespaces, namespace.NewNamespace(types.NamespaceType(*o), nil))
+			enabled = append(enabled, types.NamespaceType(*o))
+		}
+	}
+	created := map[types.NamespaceType]bool{}
+	for _, ns := range enabled {
+		if created[ns] {
+			continue
 		}
+		created[ns] = true
+		namespaces = append(namespaces, namespace.NewNamespace(ns, task))
+	}
+
+	// add task defined actions by yaml, one namespace by function
+	for _, fn := range task.GetFunctions() {
+		namespaces = append(namespaces, namespace.NewNamespace(types.NamespaceType(fn.Name), task))
 	}
 
 	// set variables
@@ -74,7 +102,7 @@ func NewState(
 		for _, action := range o.Actions() {
 			required := action.RequiredVariables()
 			if required == nil {
-				break
+				continue
 			}
 			log.Printf("actions %s requires %v\n", action.Name(), required)
 			for _, vn := range required {
@@ -89,13 +117,6 @@ func NewState(
 		}
 	}
 
-	// TODO: check the custom functions
-	// add task defined actions by yaml, if user's was set
-	if task.GetFunctions() != nil {
-		functions := task.GetFunctions()
-		namespaces = append(namespaces, namespace.NewNamespace(types.NamespaceType(task.GetName()), functions))
-	}
-
 	// set callback function
 	onEventCallback := func(event events.DisplayEvent) {
 		go func() {
@@ -123,6 +144,19 @@ func NewState(
 		}
 	}
 
+	// memories saved by the previous runs
+	if memories, exists := storages[memory.STORAGE]; exists && task.GetMemory().Persist {
+		config := task.GetMemory()
+		store, err := storage.OpenStore(config.Dir, config.Bank, task.GetSession())
+		if err == nil {
+			err = memories.Persist(store)
+		}
+		if err != nil {
+			log.Fatalf("memory bank %s", err.Error())
+		}
+		log.Printf("memory bank %s loaded\n", store.Path())
+	}
+
 	// new state
 	s.task = task
 	s.namespaces = namespaces
@@ -143,6 +177,22 @@ func NewState(
 	metrics := NewMetrics(uint(maxIterations))
 	s.metrics = metrics
 
+	// set budget
+	s.budget = NewBudget(task.GetBudget(), pricing)
+
+	// set history window
+	s.window = NewWindow(task.GetHistory())
+	s.reasoning = task.GetReasoning().History
+
+	// running summary of the evicted executions, rendered with the other storages
+	s.summarized = make(map[*Execution]bool, 0)
+	if task.GetSummary().Enable {
+		if _, exists := s.storages[SUMMARY_STORAGE]; !exists {
+			log.Printf("create storage [%s]\n", SUMMARY_STORAGE)
+			s.storages[SUMMARY_STORAGE] = storage.NewStorage(SUMMARY_STORAGE, types.CURRENTPREVIOUS, onEventCallback)
+		}
+	}
+
 	return s
 }
 
@@ -163,14 +213,35 @@ func (s *State) GetStorages() map[string]*storage.Storage {
 	return s.storages
 }
 
+// get the storage by its name, or the storage of the namespace the action belongs to
 func (s *State) GetStorage(actionName string) *storage.Storage {
-	return s.storages[actionName]
+	if st, found := s.storages[actionName]; found {
+		return st
+	}
+	for _, group := range s.namespaces {
+		for _, ac := range group.GetActions() {
+			if ac.Name() != actionName {
+				continue
+			}
+			for _, descriptor := range group.GetStrorageDescriptor() {
+				return s.storages[descriptor.Name()]
+			}
+		}
+	}
+	return nil
 }
 
 func (s *State) GetNamespaces() []*namespace.Namespace {
 	return s.namespaces
 }
 
+// releases the resources of the namespaces, e.g. the shell sessions
+func (s *State) Close() {
+	for _, ns := range s.namespaces {
+		ns.Close()
+	}
+}
+
 func (s *State) GetMaxIteration() uint {
 	return s.metrics.maxStep
 }
@@ -179,36 +250,162 @@ func (s *State) GetCurrentStep() uint {
 	return s.metrics.currentStep
 }
 
+func (s *State) IncrementStep() {
+	s.metrics.currentStep += 1
+}
+
+func (s *State) GetMetrics() *Metrics {
+	return s.metrics
+}
+
+func (s *State) GetBudget() *Budget {
+	return s.budget
+}
+
+// sub agents consume the budget of their parent
+func (s *State) SetBudget(budget *Budget) {
+	s.budget = budget
+}
+
+func (s *State) GetFocus() string {
+	return s.focus
+}
+
+func (s *State) SetFocus(focus string) {
+	s.focus = focus
+}
+
+// roll up the metrics of a finished sub agent
+func (s *State) AddMetrics(child *Metrics) {
+	s.metrics.Add(child)
+}
+
 // update history functions
 func (s *State) AddUnparsedResponseToHistory(response string, err string) {
-	s.history = append(s.history, NewExecution(&response, nil, nil, &err))
+	execution := NewExecution(&response, nil, nil, &err)
+	execution.Outcome = ERROR
+	s.history = append(s.history, execution)
 }
 
-func (s *State) AddSuccessToHistory(invocation *chat.Invocation, result *string) {
-	s.history = append(s.history, NewExecution(nil, invocation, result, nil))
+func (s *State) AddSuccessToHistory(invocation *chat.Invocation, result *string, metadata map[string]string) {
+	execution := NewExecution(nil, invocation, result, nil)
+	execution.Outcome = SUCCESS
+	execution.Metadata = metadata
+	s.history = append(s.history, execution)
 }
 
-func (s *State) AddErrorToHistory(invocation *chat.Invocation, err *string) {
-	s.history = append(s.history, NewExecution(nil, invocation, nil, err))
+func (s *State) AddSimulatedToHistory(invocation *chat.Invocation, result *string, metadata map[string]string) {
+	execution := NewExecution(nil, invocation, result, nil)
+	execution.Outcome = SIMULATED
+	execution.Metadata = metadata
+	s.history = append(s.history, execution)
 }
 
-// when this function called from `first chat“ and `on state update`
-func (s *State) ToChatHistory(max int) []*chat.Message {
-	var latest []*Execution
-	if len(s.history) > max {
-		latest = s.history[:max+1]
-	} else {
-		latest = s.history
+// outcome is one of the failed outcomes, error, timeout, rejected or skipped
+func (s *State) AddErrorToHistory(invocation *chat.Invocation, outcome Outcome, err *string) {
+	execution := NewExecution(nil, invocation, nil, err)
+	execution.Outcome = outcome
+	s.history = append(s.history, execution)
+}
+
+// corrective feedback from the engine, not tied to any invocation
+func (s *State) AddFeedbackToHistory(feedback string) {
+	s.history = append(s.history, NewExecution(nil, nil, nil, &feedback))
+}
+
+// the reasoning of a response is shared by the executions of its invocations,
+// from is the history length before they were added
+func (s *State) AttachReasoning(from int, reasoning string) {
+	if reasoning == "" {
+		return
+	}
+	for _, entry := range s.history[from:] {
+		if entry.Invocation != nil {
+			entry.Reasoning = &reasoning
+		}
+	}
+}
+
+func (s *State) sendsReasoning(reasoning, last *string) bool {
+	switch {
+	case reasoning == nil:
+		return false
+	case s.reasoning == task.REASONING_ALL:
+		return true
+	case s.reasoning == task.REASONING_LAST:
+		return reasoning == last
+	default:
+		return false
+	}
+}
+
+func (s *State) GetHistoryLength() int {
+	return len(s.history)
+}
+
+// critique written by the model in a reflection turn
+func (s *State) AddReflectionToHistory(reflection string) {
+	result := "reflection recorded, apply these lessons to your next actions"
+	s.history = append(s.history, NewExecution(&reflection, nil, &result, nil))
+}
+
+// guidance typed by the operator while the engine is running
+func (s *State) AddOperatorMessageToHistory(message string) {
+	execution := NewExecution(nil, nil, nil, nil)
+	execution.Operator = &message
+	s.history = append(s.history, execution)
+}
+
+// executions evicted from the history window and not summarized yet
+func (s *State) GetUnsummarized() []*Execution {
+	_, evicted := s.window.Select(s.history)
+	unsummarized := []*Execution{}
+	for _, entry := range evicted {
+		if !s.summarized[entry] {
+			unsummarized = append(unsummarized, entry)
+		}
+	}
+	return unsummarized
+}
+
+func (s *State) SetSummarized(executions []*Execution) {
+	for _, entry := range executions {
+		s.summarized[entry] = true
 	}
+}
+
+func (s *State) GetHistory() []*Execution {
+	return s.history
+}
 
-	if latest == nil {
+// when this function called from `first chat“ and `on state update`
+func (s *State) ToChatHistory() []*chat.Message {
+	latest, _ := s.window.Select(s.history)
+	if len(latest) == 0 {
 		return nil
 	}
 
+	// reasoning of the latest response, for the last policy
+	var lastReasoning *string
+	for i := len(s.history) - 1; i >= 0 && lastReasoning == nil; i-- {
+		lastReasoning = s.history[i].Reasoning
+	}
+
 	// to messages
 	// todo: historyの内容が正しいか？
 	history := []*chat.Message{}
+	var prevReasoning *string
 	for _, entry := range latest {
+		// operator messages have no feedback
+		if entry.Operator != nil {
+			history = append(history, &chat.Message{
+				MessageType: chat.OPERATOR,
+				Response:    entry.Operator,
+				Invocation:  nil,
+			})
+			continue
+		}
+
 		// agent messages
 		if entry.Response != nil {
 			history = append(history, &chat.Message{
@@ -217,19 +414,32 @@ func (s *State) ToChatHistory(max int) []*chat.Message {
 				Invocation:  nil,
 			})
 		} else if entry.Invocation != nil {
+			// parse to invocation to string,
+			// to including the results of executing a "function call" when executing factory.Chat()
+			response := s.SerializeInvocation(entry.Invocation)
+
+			// the reasoning precedes the first invocation of its response
+			if s.sendsReasoning(entry.Reasoning, lastReasoning) && entry.Reasoning != prevReasoning {
+				withReasoning := fmt.Sprintf("%s\n\n%s", *entry.Reasoning, *response)
+				response = &withReasoning
+			}
+			prevReasoning = entry.Reasoning
+
 			history = append(history, &chat.Message{
 				MessageType: chat.AGETNT,
-				// parse to invocation to string,
-				// to including the results of executing a "function call" when executing factory.Chat()
-				Response:   s.SerializeInvocation(entry.Invocation),
-				Invocation: entry.Invocation,
+				Response:    response,
+				Invocation:  entry.Invocation,
 			})
 		}
 
 		// feedback messages
 		var res string
 		if entry.Error != nil {
-			res = fmt.Sprintf("ERROR: %s", *entry.Error)
+			outcome := ERROR
+			if entry.Outcome != "" && entry.Outcome != SUCCESS {
+				outcome = entry.Outcome
+			}
+			res = fmt.Sprintf("%s: %s", strings.ToUpper(string(outcome)), *entry.Error)
 		} else if entry.Result != nil {
 			res = *entry.Result
 		} else {
@@ -275,6 +485,22 @@ func (s *State) IncrementSuccessActionMetrics() {
 	s.metrics.successActions += 1
 }
 
+func (s *State) IncrementInvalidMetrics() {
+	s.metrics.errors.invalidActions += 1
+}
+
+func (s *State) IncrementSimulatedActionMetrics() {
+	s.metrics.simulatedActions += 1
+}
+
+func (s *State) IncrementRejectedActionMetrics() {
+	s.metrics.errors.rejectedActions += 1
+}
+
+func (s *State) IncrementSkippedActionMetrics() {
+	s.metrics.errors.skippedActions += 1
+}
+
 func (s *State) IncrementTimeoutActionMetrics() {
 	s.metrics.errors.timedoutActions += 1
 }
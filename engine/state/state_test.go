@@ -0,0 +1,49 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/task"
+)
+
+func Test_StateNamespaces(t *testing.T) {
+	cb := func(inv *chat.Invocation) *string {
+		s := inv.FunctionCallString()
+		return &s
+	}
+	prompt := "find the open ports"
+	all, shell := "*", "shell"
+
+	cases := []struct {
+		using   []*string
+		count   int
+		enabled []string
+		missing []string
+	}{
+		// the namespaces added since are enabled by the task
+		{nil, 5, []string{"save_memory", "task_complete", "shell"}, []string{"http_request", "delegate_task", "read_file"}},
+		{[]*string{&shell, &all}, 10, []string{"save_memory", "task_complete", "http_request", "read_file"}, nil},
+	}
+	for i, c := range cases {
+		tk := &task.Task{Prompt: &prompt, Using: c.using}
+		s := NewState(events.NewChannel(), tk, 0, nil, cb)
+		if len(s.GetNamespaces()) != c.count {
+			t.Fatalf("case %d: expected %d namespaces, got %d", i, c.count, len(s.GetNamespaces()))
+		}
+		for _, name := range c.enabled {
+			if s.GetAciton(name) == nil {
+				t.Fatalf("case %d: %s is missing", i, name)
+			}
+		}
+		for _, name := range c.missing {
+			if s.GetAciton(name) != nil {
+				t.Fatalf("case %d: %s is enabled", i, name)
+			}
+		}
+		if c.using != nil && *c.using[1] != "*" {
+			t.Fatal("the using of the task was changed")
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/runetale/notch/task"
+)
+
+// selects the executions sent to the model as chat history
+type Window struct {
+	strategy task.HistoryStrategy
+	max      int
+	pinned   int
+}
+
+func NewWindow(config task.History) *Window {
+	return &Window{
+		strategy: config.Strategy,
+		max:      int(config.Max),
+		pinned:   int(config.Pinned),
+	}
+}
+
+// returns the kept executions in their original order
+// and the evicted ones, also in order
+func (w *Window) Select(history []*Execution) ([]*Execution, []*Execution) {
+	if w.max <= 0 || len(history) <= w.max {
+		return history, nil
+	}
+
+	switch w.strategy {
+	case task.HISTORY_PINNED:
+		return w.selectPinned(history)
+	case task.HISTORY_ERRORS:
+		return w.selectErrors(history)
+	case task.HISTORY_SUMMARIZE:
+		// one entry of the window is used by the summary
+		kept := history[len(history)-(w.max-1):]
+		evicted := history[:len(history)-(w.max-1)]
+		return append([]*Execution{summarize(evicted)}, kept...), evicted
+	default:
+		return history[len(history)-w.max:], history[:len(history)-w.max]
+	}
+}
+
+// the first pinned executions and the last max-pinned
+func (w *Window) selectPinned(history []*Execution) ([]*Execution, []*Execution) {
+	recent := w.max - w.pinned
+	kept := make([]*Execution, 0, w.max)
+	kept = append(kept, history[:w.pinned]...)
+	kept = append(kept, history[len(history)-recent:]...)
+	return kept, history[w.pinned : len(history)-recent]
+}
+
+// the last max executions, plus up to max older errors
+func (w *Window) selectErrors(history []*Execution) ([]*Execution, []*Execution) {
+	older := history[:len(history)-w.max]
+
+	errored := 0
+	keep := make([]bool, len(older))
+	for i := len(older) - 1; i >= 0 && errored < w.max; i-- {
+		if older[i].Error != nil {
+			keep[i] = true
+			errored++
+		}
+	}
+
+	kept := make([]*Execution, 0, w.max+errored)
+	evicted := make([]*Execution, 0, len(older)-errored)
+	for i, entry := range older {
+		if keep[i] {
+			kept = append(kept, entry)
+		} else {
+			evicted = append(evicted, entry)
+		}
+	}
+	kept = append(kept, history[len(history)-w.max:]...)
+	return kept, evicted
+}
+
+// deterministic summary of the evicted executions
+func summarize(evicted []*Execution) *Execution {
+	counts := make(map[string]int, 0)
+	succeeded, failed := 0, 0
+	for _, entry := range evicted {
+		if entry.Invocation == nil {
+			continue
+		}
+		counts[entry.Invocation.Action]++
+		if entry.Error != nil {
+			failed++
+		} else {
+			succeeded++
+		}
+	}
+
+	actions := make([]string, 0, len(counts))
+	for name := range counts {
+		actions = append(actions, name)
+	}
+	sort.Strings(actions)
+	for i, name := range actions {
+		actions[i] = fmt.Sprintf("%s x%d", name, counts[name])
+	}
+
+	summary := fmt.Sprintf("%d earlier executions are not shown (%d succeeded, %d failed)", len(evicted), succeeded, failed)
+	if len(actions) > 0 {
+		summary += fmt.Sprintf(", actions used: %s", strings.Join(actions, ", "))
+	}
+	summary += ". Use your memories for what was found."
+
+	// a result without invocation is rendered as a feedback message
+	return NewExecution(nil, nil, &summary, nil)
+}
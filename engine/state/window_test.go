@@ -0,0 +1,125 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/task"
+)
+
+// executions with payload "0".."n-1", the ones in errored have an error
+func numbered(n int, errored ...int) []*Execution {
+	history := make([]*Execution, n)
+	for i := range history {
+		payload := fmt.Sprintf("%d", i)
+		history[i] = NewExecution(nil, chat.NewInvocation("shell", nil, &payload), &payload, nil)
+	}
+	for _, i := range errored {
+		err := "failed"
+		history[i].Result = nil
+		history[i].Error = &err
+	}
+	return history
+}
+
+func payloads(history []*Execution) string {
+	parts := []string{}
+	for _, entry := range history {
+		if entry.Invocation == nil {
+			parts = append(parts, "summary")
+		} else {
+			parts = append(parts, *entry.Invocation.Payload)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func Test_WindowRecent(t *testing.T) {
+	w := NewWindow(task.History{Strategy: task.HISTORY_RECENT, Max: 3})
+
+	cases := []struct {
+		size    int
+		kept    string
+		evicted string
+	}{
+		{0, "", ""},
+		{2, "0,1", ""},
+		{3, "0,1,2", ""},
+		{4, "1,2,3", "0"},
+		{6, "3,4,5", "0,1,2"},
+	}
+	for _, c := range cases {
+		kept, evicted := w.Select(numbered(c.size))
+		if payloads(kept) != c.kept || payloads(evicted) != c.evicted {
+			t.Fatalf("size %d: kept %s evicted %s, want %s and %s",
+				c.size, payloads(kept), payloads(evicted), c.kept, c.evicted)
+		}
+	}
+}
+
+func Test_WindowPinned(t *testing.T) {
+	w := NewWindow(task.History{Strategy: task.HISTORY_PINNED, Max: 4, Pinned: 2})
+
+	kept, evicted := w.Select(numbered(4))
+	if payloads(kept) != "0,1,2,3" || len(evicted) != 0 {
+		t.Fatalf("unexpected window %s", payloads(kept))
+	}
+
+	kept, evicted = w.Select(numbered(5))
+	if payloads(kept) != "0,1,3,4" || payloads(evicted) != "2" {
+		t.Fatalf("unexpected window %s evicted %s", payloads(kept), payloads(evicted))
+	}
+
+	kept, evicted = w.Select(numbered(8))
+	if payloads(kept) != "0,1,6,7" || payloads(evicted) != "2,3,4,5" {
+		t.Fatalf("unexpected window %s evicted %s", payloads(kept), payloads(evicted))
+	}
+}
+
+func Test_WindowErrors(t *testing.T) {
+	w := NewWindow(task.History{Strategy: task.HISTORY_ERRORS, Max: 2})
+
+	kept, evicted := w.Select(numbered(6, 1, 3, 5))
+	if payloads(kept) != "1,3,4,5" || payloads(evicted) != "0,2" {
+		t.Fatalf("unexpected window %s evicted %s", payloads(kept), payloads(evicted))
+	}
+
+	// older errors are limited to max
+	kept, evicted = w.Select(numbered(7, 0, 1, 2, 3))
+	if payloads(kept) != "2,3,5,6" || payloads(evicted) != "0,1,4" {
+		t.Fatalf("unexpected window %s evicted %s", payloads(kept), payloads(evicted))
+	}
+}
+
+func Test_WindowSummarize(t *testing.T) {
+	w := NewWindow(task.History{Strategy: task.HISTORY_SUMMARIZE, Max: 3})
+
+	kept, evicted := w.Select(numbered(3))
+	if payloads(kept) != "0,1,2" || len(evicted) != 0 {
+		t.Fatalf("unexpected window %s", payloads(kept))
+	}
+
+	kept, evicted = w.Select(numbered(5, 0))
+	if payloads(kept) != "summary,3,4" || payloads(evicted) != "0,1,2" {
+		t.Fatalf("unexpected window %s evicted %s", payloads(kept), payloads(evicted))
+	}
+	summary := *kept[0].Result
+	if !strings.Contains(summary, "3 earlier executions") || !strings.Contains(summary, "1 failed") {
+		t.Fatalf("unexpected summary %s", summary)
+	}
+}
+
+func Test_TaskHistoryDefaults(t *testing.T) {
+	tk := &task.Task{}
+	h := tk.GetHistory()
+	if h.Strategy != task.HISTORY_RECENT || h.Max != 50 {
+		t.Fatalf("unexpected defaults %+v", h)
+	}
+
+	tk.History = &task.History{Strategy: task.HISTORY_PINNED, Max: 3, Pinned: 10}
+	if h := tk.GetHistory(); h.Pinned != 3 {
+		t.Fatalf("pinned must not exceed max, got %d", h.Pinned)
+	}
+}
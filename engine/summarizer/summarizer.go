@@ -0,0 +1,52 @@
+// condenses executions evicted from the history window into a running summary
+package summarizer
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/runetale/notch/engine/chat"
+	"github.com/runetale/notch/engine/serializer"
+	"github.com/runetale/notch/engine/state"
+	"github.com/runetale/notch/llm"
+)
+
+//go:embed system.prompt
+var systemPrompt string
+
+type Summarizer struct {
+	factory *llm.LLMFactory
+}
+
+func NewSummarizer(factory *llm.LLMFactory) *Summarizer {
+	return &Summarizer{
+		factory: factory,
+	}
+}
+
+// returns the previous summary updated with the executions
+func (s *Summarizer) Summarize(previous string, executions []*state.Execution) (string, llm.Usage, error) {
+	var prompt strings.Builder
+	prompt.WriteString("# Previous summary\n\n")
+	if previous == "" {
+		prompt.WriteString("none\n")
+	} else {
+		prompt.WriteString(previous + "\n")
+	}
+
+	prompt.WriteString("\n# New executions\n\n")
+	for _, entry := range executions {
+		prompt.WriteString(serializer.DisplayExecution(entry))
+		prompt.WriteString("\n")
+	}
+
+	option := chat.NewChatOption(systemPrompt, prompt.String(), nil)
+	_, response, usage := s.factory.Chat(option, false, nil)
+
+	summary := strings.TrimSpace(response)
+	if summary == "" {
+		return previous, usage, fmt.Errorf("empty summary for %d executions", len(executions))
+	}
+	return summary, usage, nil
+}
@@ -1,8 +1,10 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/runetale/notch/types"
@@ -21,6 +23,14 @@ const (
 	ActionExecuted  EventType = "aciton_executed"
 	TaskComplete    EventType = "task_comlete"
 	EmptyResponse   EventType = "empty_response"
+	LoopDetected    EventType = "loop_detected"
+	Stopped         EventType = "stopped"
+	SubAgent        EventType = "sub_agent"
+	OperatorMessage EventType = "operator_message"
+	ActionSkipped   EventType = "action_skipped"
+	ActionSimulated EventType = "action_simulated"
+	Reflection      EventType = "reflection"
+	Reasoning       EventType = "reasoning"
 )
 
 type DisplayEvent interface {
@@ -219,3 +229,156 @@ func NewEmptyResponseEvent() DisplayEvent {
 func (e *EmptyResponseEvent) Display() string {
 	return "agent did not provide valid instructions: empty response"
 }
+
+type LoopDetectedEvent struct {
+	loop        string
+	consecutive int
+}
+
+func NewLoopDetectedEvent(loop string, consecutive int) DisplayEvent {
+	return &LoopDetectedEvent{
+		loop:        loop,
+		consecutive: consecutive,
+	}
+}
+
+func (e *LoopDetectedEvent) Display() string {
+	return fmt.Sprintf("loop detected (%d in a row): %s", e.consecutive, e.loop)
+}
+
+type StoppedEvent struct {
+	reason string
+}
+
+func NewStoppedEvent(reason string) DisplayEvent {
+	return &StoppedEvent{
+		reason: reason,
+	}
+}
+
+func (e *StoppedEvent) Display() string {
+	return fmt.Sprintf("run stopped: %s", e.reason)
+}
+
+type SubAgentEvent struct {
+	goal   string
+	result *string
+}
+
+// result is nil when the sub agent starts
+func NewSubAgentEvent(goal string, result *string) DisplayEvent {
+	return &SubAgentEvent{
+		goal:   goal,
+		result: result,
+	}
+}
+
+func (e *SubAgentEvent) Display() string {
+	if e.result == nil {
+		return fmt.Sprintf("sub agent started: %s", e.goal)
+	}
+	return fmt.Sprintf("sub agent finished '%s' -> %s", e.goal, *e.result)
+}
+
+type OperatorMessageEvent struct {
+	message string
+}
+
+func NewOperatorMessageEvent(message string) DisplayEvent {
+	return &OperatorMessageEvent{
+		message: message,
+	}
+}
+
+func (e *OperatorMessageEvent) Display() string {
+	return fmt.Sprintf("operator > %s", e.message)
+}
+
+type ActionSkippedEvent struct {
+	invocation string
+	reason     string
+}
+
+func NewActionSkippedEvent(inv, reason string) DisplayEvent {
+	return &ActionSkippedEvent{
+		invocation: inv,
+		reason:     reason,
+	}
+}
+
+func (e *ActionSkippedEvent) Display() string {
+	return fmt.Sprintf("%s skipped: %s", e.invocation, e.reason)
+}
+
+type ActionSimulatedEvent struct {
+	invocation string
+	result     string
+}
+
+func NewActionSimulatedEvent(inv, result string) DisplayEvent {
+	return &ActionSimulatedEvent{
+		invocation: inv,
+		result:     result,
+	}
+}
+
+func (e *ActionSimulatedEvent) Display() string {
+	return fmt.Sprintf("dry run %s -> %s", e.invocation, e.result)
+}
+
+type ReflectionEvent struct {
+	reason string
+}
+
+func NewReflectionEvent(reason string) DisplayEvent {
+	return &ReflectionEvent{
+		reason: reason,
+	}
+}
+
+func (e *ReflectionEvent) Display() string {
+	return fmt.Sprintf("reflection turn, %s", e.reason)
+}
+
+type ReasoningEvent struct {
+	Time        time.Time `json:"time"`
+	Step        uint      `json:"step"`
+	Reasoning   string    `json:"reasoning"`
+	Invocations []string  `json:"invocations"`
+}
+
+func NewReasoningEvent(step uint, reasoning string, invocations []string) *ReasoningEvent {
+	return &ReasoningEvent{
+		Time:        time.Now(),
+		Step:        step,
+		Reasoning:   reasoning,
+		Invocations: invocations,
+	}
+}
+
+func (e *ReasoningEvent) Display() string {
+	return fmt.Sprintf("reasoning of step %d: %s", e.Step, e.Reasoning)
+}
+
+// the engines of a run append to the same file
+var exportMu sync.Mutex
+
+// appends a json line to the file, called when the reasoning is recorded
+// since the events are delivered concurrently
+func (e *ReasoningEvent) Export(path string) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	exportMu.Lock()
+	defer exportMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// embeds texts into vectors, for the rag namespace
+type EmbedderImpl interface {
+	Name() string
+	Embed(texts []string) ([][]float32, error)
+}
+
+// embedder of the generator string, e.g. openai://text-embedding-3-small
+func NewEmbedder(generator string, apiKey string) (EmbedderImpl, error) {
+	options, err := NewLLMOptions(generator, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch options.typeName {
+	case OpenAI:
+		return &OpenAIEmbedder{
+			model:  options.modelName,
+			client: openai.NewClient(apiKey),
+		}, nil
+	}
+	return nil, errors.New("not suuported embedder")
+}
+
+type OpenAIEmbedder struct {
+	model  string
+	client *openai.Client
+}
+
+func (o *OpenAIEmbedder) Name() string {
+	return fmt.Sprintf("%s://%s", OpenAI, o.model)
+}
+
+func (o *OpenAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	resp, err := o.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(o.model),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
@@ -8,8 +8,18 @@ import (
 	"github.com/runetale/notch/engine/namespace"
 )
 
+// tokens consumed by a single chat request
+type Usage struct {
+	PromptTokens     uint64
+	CompletionTokens uint64
+}
+
+func (u Usage) Total() uint64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
 type LLMClientImpl interface {
-	Chat(option *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string)
+	Chat(option *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string, Usage)
 	CheckNatvieToolSupport() bool
 }
 
@@ -19,6 +29,7 @@ type LLMFactory struct {
 	contextWindow uint32
 	host          string
 	port          uint16
+	apiKey        string
 
 	client LLMClientImpl
 }
@@ -35,10 +46,21 @@ func NewLLMFactory(options LLMOptions, apiKey string) (*LLMFactory, error) {
 		contextWindow: options.contextWindow,
 		host:          options.host,
 		port:          options.port,
+		apiKey:        apiKey,
 		client:        client,
 	}, nil
 }
 
+// factory of another generator with the same api key and context window,
+// e.g. a cheaper model for secondary tasks
+func (c *LLMFactory) NewFromGenerator(generator string) (*LLMFactory, error) {
+	options, err := NewLLMOptions(generator, c.contextWindow)
+	if err != nil {
+		return nil, err
+	}
+	return NewLLMFactory(options, c.apiKey)
+}
+
 func newLLMFactory(llmType LLMTypeName, options LLMOptions, apiKey string) (LLMClientImpl, error) {
 	switch llmType {
 	case Ollama:
@@ -53,10 +75,14 @@ func newLLMFactory(llmType LLMTypeName, options LLMOptions, apiKey string) (LLMC
 	return nil, errors.New("not suuported llm")
 }
 
-func (c *LLMFactory) Chat(options *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string) {
+func (c *LLMFactory) Chat(options *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string, Usage) {
 	return c.client.Chat(options, nativeSupport, namespaces)
 }
 
+func (c *LLMFactory) GetModelName() string {
+	return c.modelName
+}
+
 func (c *LLMFactory) CheckNatvieToolSupport() bool {
 	return c.client.CheckNatvieToolSupport()
 }
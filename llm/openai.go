@@ -84,7 +84,7 @@ func NewOpenAIClient(model string, apikey string, url string, port uint16) LLMCl
 	}
 }
 
-func (o *OpenAIClient) Chat(option *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string) {
+func (o *OpenAIClient) Chat(option *chat.ChatOption, nativeSupport bool, namespaces []*namespace.Namespace) ([]*chat.Invocation, string, Usage) {
 	chathistory := []openai.ChatCompletionMessage{
 		{
 			Role:      openai.ChatMessageRoleSystem,
@@ -114,6 +114,13 @@ func (o *OpenAIClient) Chat(option *chat.ChatOption, nativeSupport bool, namespa
 					Content:   *m.Response,
 					ToolCalls: nil,
 				})
+			case chat.OPERATOR:
+				chathistory = append(chathistory, openai.ChatCompletionMessage{
+					Role:      openai.ChatMessageRoleUser,
+					Name:      "operator",
+					Content:   fmt.Sprintf("OPERATOR: %s", *m.Response),
+					ToolCalls: nil,
+				})
 			}
 		}
 	}
@@ -219,7 +226,12 @@ func (o *OpenAIClient) Chat(option *chat.ChatOption, nativeSupport bool, namespa
 		invocations = append(invocations, in)
 	}
 
-	return invocations, content
+	usage := Usage{
+		PromptTokens:     uint64(resp.Usage.PromptTokens),
+		CompletionTokens: uint64(resp.Usage.CompletionTokens),
+	}
+
+	return invocations, content, usage
 }
 
 func (o *OpenAIClient) CheckNatvieToolSupport() bool {
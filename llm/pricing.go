@@ -0,0 +1,41 @@
+package llm
+
+import "strings"
+
+// USD per million tokens
+type Pricing struct {
+	Input  float64
+	Output float64
+}
+
+// known list prices, longer prefixes are matched first
+var pricings = []struct {
+	prefix  string
+	pricing Pricing
+}{
+	{GPT4oMini, Pricing{Input: 0.15, Output: 0.6}},
+	{GPT4oLatest, Pricing{Input: 5, Output: 15}},
+	{GPT4o, Pricing{Input: 2.5, Output: 10}},
+	{O1Mini, Pricing{Input: 3, Output: 12}},
+	{O1Preview, Pricing{Input: 15, Output: 60}},
+	{GPT4Turbo, Pricing{Input: 10, Output: 30}},
+	{GPT432K, Pricing{Input: 60, Output: 120}},
+	{GPT4Turbo1106, Pricing{Input: 10, Output: 30}},
+	{GPT4Turbo0125, Pricing{Input: 10, Output: 30}},
+	{GPT4, Pricing{Input: 30, Output: 60}},
+	{GPT3Dot5Turbo, Pricing{Input: 0.5, Output: 1.5}},
+}
+
+// returns false if the model has no known price
+func GetPricing(model string) (Pricing, bool) {
+	for _, p := range pricings {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.pricing, true
+		}
+	}
+	return Pricing{}, false
+}
+
+func (p Pricing) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1_000_000
+}
@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runetale/notch/events"
+)
+
+type StepStatus string
+
+const (
+	PENDING     StepStatus = "pending"
+	IN_PROGRESS StepStatus = "in-progress"
+	BLOCKED     StepStatus = "blocked"
+	DONE        StepStatus = "done"
+	FAILED      StepStatus = "failed"
+)
+
+func ParseStepStatus(value string) (StepStatus, error) {
+	status := StepStatus(strings.ToLower(strings.TrimSpace(value)))
+	switch status {
+	case PENDING, IN_PROGRESS, BLOCKED, DONE, FAILED:
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid status '%s', expected pending, in-progress, blocked, done or failed", value)
+}
+
+// a step of a plan of completion storages
+type step struct {
+	data    string
+	status  StepStatus
+	time    time.Time
+	steps   []*step
+	depends []*step
+}
+
+func newStep(data string) *step {
+	return &step{
+		data:   data,
+		status: PENDING,
+		time:   time.Now(),
+	}
+}
+
+// a step with sub steps takes its status from them
+func (st *step) state() StepStatus {
+	if len(st.steps) == 0 {
+		return st.status
+	}
+	counts := map[StepStatus]int{}
+	for _, sub := range st.steps {
+		counts[sub.state()]++
+	}
+	switch {
+	case counts[DONE] == len(st.steps):
+		return DONE
+	case counts[FAILED] > 0:
+		return FAILED
+	case counts[IN_PROGRESS] > 0 || counts[DONE] > 0:
+		return IN_PROGRESS
+	case counts[BLOCKED] > 0:
+		return BLOCKED
+	}
+	return PENDING
+}
+
+func (st *step) contains(other *step) bool {
+	if st == other {
+		return true
+	}
+	for _, sub := range st.steps {
+		if sub.contains(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// a plan step in depth first order
+type Step struct {
+	// position such as 2 or 2.1
+	Path   string
+	Depth  int
+	Data   string
+	Status StepStatus
+	Time   time.Time
+	// paths of the steps it waits for
+	Depends []string
+	// a dependency of the step or of a parent step is not done
+	Waiting bool
+	Leaf    bool
+}
+
+// the step can be worked on
+func (s *Step) Actionable() bool {
+	return s.Leaf && !s.Waiting && (s.Status == PENDING || s.Status == IN_PROGRESS)
+}
+
+// the step is added after the last position
+func (s *Storage) AddCompletion(data string) {
+	s.AddStep("", data)
+}
+
+// adds a step after the last sub step of the parent, or of the plan if the parent is empty.
+// returns the position of the step
+func (s *Storage) AddStep(parent string, data string) (string, error) {
+	s.mu.Lock()
+	siblings := &s.steps
+	if parent != "" {
+		st, err := s.find(parent)
+		if err != nil {
+			s.mu.Unlock()
+			return "", err
+		}
+		siblings = &st.steps
+	}
+	*siblings = append(*siblings, newStep(data))
+	if s.cyclic() {
+		*siblings = (*siblings)[:len(*siblings)-1]
+		s.mu.Unlock()
+		return "", fmt.Errorf("a step under %s would wait for itself", parent)
+	}
+	path := strconv.Itoa(len(*siblings))
+	if parent != "" {
+		path = strings.TrimSpace(parent) + "." + path
+	}
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, nil, &data))
+	return path, nil
+}
+
+func (s *Storage) InsertCompletion(pos int, data string) error {
+	return s.InsertStep(strconv.Itoa(pos), data)
+}
+
+// the step takes the position, the next steps move down. the position after the last step appends it
+func (s *Storage) InsertStep(path string, data string) error {
+	s.mu.Lock()
+	siblings, index, err := s.slot(path)
+	if err == nil && index > len(*siblings) {
+		err = fmt.Errorf("invalid position %s, there are %d steps at this level", path, len(*siblings))
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	insert(siblings, index, newStep(data))
+	if s.cyclic() {
+		remove(siblings, index)
+		s.mu.Unlock()
+		return fmt.Errorf("a step at %s would wait for itself", path)
+	}
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, nil, &data))
+	return nil
+}
+
+func (s *Storage) DelCompletion(pos int) error {
+	return s.DelStep(strconv.Itoa(pos))
+}
+
+// removes the step with its sub steps, the next steps move up
+func (s *Storage) DelStep(path string) error {
+	s.mu.Lock()
+	siblings, index, err := s.slot(path)
+	if err == nil && index == len(*siblings) {
+		err = s.missing(path)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old := (*siblings)[index]
+	remove(siblings, index)
+	// the other steps no longer wait for the removed ones
+	s.walk(s.steps, func(st *step) {
+		depends := st.depends[:0]
+		for _, dep := range st.depends {
+			if !old.contains(dep) {
+				depends = append(depends, dep)
+			}
+		}
+		st.depends = depends
+	})
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, &old.data, nil))
+	return nil
+}
+
+func (s *Storage) MoveCompletion(from, to int) error {
+	return s.MoveStep(strconv.Itoa(from), strconv.Itoa(to))
+}
+
+// the step and its sub steps get the position to, counted once the step is taken out of the plan
+func (s *Storage) MoveStep(from, to string) error {
+	s.mu.Lock()
+	siblings, index, err := s.slot(from)
+	if err == nil && index == len(*siblings) {
+		err = s.missing(from)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	moved := (*siblings)[index]
+	remove(siblings, index)
+
+	target, at, err := s.slot(to)
+	if err == nil && at > len(*target) {
+		err = fmt.Errorf("invalid position %s, there are %d steps at this level", to, len(*target))
+	}
+	if err == nil {
+		insert(target, at, moved)
+		// under a step it waits for, or one waiting for it
+		if s.cyclic() {
+			remove(target, at)
+			err = fmt.Errorf("step %s can not move to %s, it would wait for itself", from, to)
+		}
+	}
+	if err != nil {
+		// the plan is left as it was
+		insert(siblings, index, moved)
+		s.mu.Unlock()
+		return err
+	}
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, to, &moved.data, &moved.data))
+	return nil
+}
+
+func (s *Storage) RenameCompletion(pos int, data string) error {
+	return s.RenameStep(strconv.Itoa(pos), data)
+}
+
+// changes the text of a step, its status is kept
+func (s *Storage) RenameStep(path string, data string) error {
+	s.mu.Lock()
+	st, err := s.find(path)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old := st.data
+	st.data = data
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, &old, &data))
+	return nil
+}
+
+func (s *Storage) SetComplete(pos int) error {
+	return s.SetStepStatus(strconv.Itoa(pos), DONE)
+}
+
+func (s *Storage) SetInComplete(pos int) error {
+	return s.SetStepStatus(strconv.Itoa(pos), PENDING)
+}
+
+// only steps without sub steps have their own status
+func (s *Storage) SetStepStatus(path string, status StepStatus) error {
+	s.mu.Lock()
+	st, err := s.find(path)
+	if err == nil && len(st.steps) > 0 {
+		err = fmt.Errorf("step %s takes its status from its sub steps", path)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	prev := string(st.status)
+	st.status = status
+	s.mu.Unlock()
+
+	next := string(status)
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, &prev, &next))
+	return nil
+}
+
+// the step waits until the other one is done
+func (s *Storage) AddDependency(path, on string) error {
+	s.mu.Lock()
+	st, err := s.find(path)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	dep, err := s.find(on)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	switch {
+	case st.contains(dep) || dep.contains(st):
+		err = fmt.Errorf("step %s can not wait for step %s, one is part of the other", path, on)
+	case s.waitsFor(dep, st, map[*step]bool{}):
+		err = fmt.Errorf("step %s already waits for step %s", on, path)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	for _, existing := range st.depends {
+		if existing == dep {
+			s.mu.Unlock()
+			return nil
+		}
+	}
+	st.depends = append(st.depends, dep)
+	s.mu.Unlock()
+
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, nil, &on))
+	return nil
+}
+
+func (s *Storage) RemoveDependency(path, on string) error {
+	s.mu.Lock()
+	st, err := s.find(path)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	dep, err := s.find(on)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	found := false
+	depends := st.depends[:0]
+	for _, existing := range st.depends {
+		if existing == dep {
+			found = true
+			continue
+		}
+		depends = append(depends, existing)
+	}
+	st.depends = depends
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("step %s does not wait for step %s", path, on)
+	}
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, path, &on, nil))
+	return nil
+}
+
+// the steps of the plan in depth first order
+func (s *Storage) GetSteps() []*Step {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths := map[*step]string{}
+	var index func(steps []*step, prefix string)
+	index = func(steps []*step, prefix string) {
+		for i, st := range steps {
+			paths[st] = prefix + strconv.Itoa(i+1)
+			index(st.steps, paths[st]+".")
+		}
+	}
+	index(s.steps, "")
+
+	result := []*Step{}
+	var flatten func(steps []*step, depth int, waiting bool)
+	flatten = func(steps []*step, depth int, waiting bool) {
+		for _, st := range steps {
+			view := &Step{
+				Path:    paths[st],
+				Depth:   depth,
+				Data:    st.data,
+				Status:  st.state(),
+				Time:    st.time,
+				Waiting: waiting,
+				Leaf:    len(st.steps) == 0,
+			}
+			for _, dep := range st.depends {
+				view.Depends = append(view.Depends, paths[dep])
+				if dep.state() != DONE {
+					view.Waiting = true
+				}
+			}
+			result = append(result, view)
+			flatten(st.steps, depth+1, view.Waiting)
+		}
+	}
+	flatten(s.steps, 0, false)
+	return result
+}
+
+// called with the lock held, the step at the path
+func (s *Storage) find(path string) (*step, error) {
+	siblings, index, err := s.slot(path)
+	if err != nil {
+		return nil, err
+	}
+	if index == len(*siblings) {
+		return nil, s.missing(path)
+	}
+	return (*siblings)[index], nil
+}
+
+// called with the lock held, the steps of the parent and the index of the path in them,
+// the index can be the one after the last step
+func (s *Storage) slot(path string) (*[]*step, int, error) {
+	parts := strings.Split(strings.TrimSpace(path), ".")
+	positions := make([]int, len(parts))
+	for i, part := range parts {
+		pos, err := strconv.Atoi(part)
+		if err != nil || pos < 1 {
+			return nil, 0, fmt.Errorf("invalid position '%s', expected the number of a plan step such as 2 or 2.1", path)
+		}
+		positions[i] = pos
+	}
+
+	siblings := &s.steps
+	for _, pos := range positions[:len(positions)-1] {
+		if pos > len(*siblings) {
+			return nil, 0, s.missing(path)
+		}
+		siblings = &(*siblings)[pos-1].steps
+	}
+	index := positions[len(positions)-1] - 1
+	if index > len(*siblings) {
+		return nil, 0, s.missing(path)
+	}
+	return siblings, index, nil
+}
+
+func (s *Storage) missing(path string) error {
+	return fmt.Errorf("no step at position %s, the plan has %d steps", path, len(s.steps))
+}
+
+func (s *Storage) walk(steps []*step, visit func(st *step)) {
+	for _, st := range steps {
+		visit(st)
+		s.walk(st.steps, visit)
+	}
+}
+
+// the step waits for the other one, directly or through other steps.
+// a step also waits for the dependencies of its parents and of its sub steps
+func (s *Storage) waitsFor(st, other *step, seen map[*step]bool) bool {
+	if seen[st] {
+		return false
+	}
+	seen[st] = true
+
+	depends := []*step{}
+	for _, parent := range s.parents(st) {
+		depends = append(depends, parent.depends...)
+	}
+	s.walk([]*step{st}, func(sub *step) {
+		depends = append(depends, sub.depends...)
+	})
+	for _, dep := range depends {
+		if other.contains(dep) || dep.contains(other) || s.waitsFor(dep, other, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// called with the lock held, a step waits for itself after a change of the plan structure
+func (s *Storage) cyclic() bool {
+	found := false
+	s.walk(s.steps, func(st *step) {
+		for _, dep := range st.depends {
+			if !found && (st.contains(dep) || dep.contains(st) || s.waitsFor(dep, st, map[*step]bool{})) {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+func (s *Storage) parents(st *step) []*step {
+	parents := []*step{}
+	s.walk(s.steps, func(parent *step) {
+		if parent != st && parent.contains(st) {
+			parents = append(parents, parent)
+		}
+	})
+	return parents
+}
+
+func insert(steps *[]*step, index int, st *step) {
+	*steps = append(*steps, nil)
+	copy((*steps)[index+1:], (*steps)[index:])
+	(*steps)[index] = st
+}
+
+func remove(steps *[]*step, index int) {
+	*steps = append((*steps)[:index], (*steps)[index+1:]...)
+}
@@ -1,8 +1,9 @@
 package storage
 
 import (
+	"log"
 	"sort"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/runetale/notch/events"
@@ -10,16 +11,14 @@ import (
 )
 
 type Entry struct {
-	Time     time.Time
-	Complete bool // for Completion storage
-	Data     string
+	Time time.Time
+	Data string
 }
 
 func NewEntry(data string) *Entry {
 	return &Entry{
-		Time:     time.Now(),
-		Complete: false,
-		Data:     data,
+		Time: time.Now(),
+		Data: data,
 	}
 }
 
@@ -27,10 +26,16 @@ const CURRENT_TAG = "current"
 const PREVIOUS_TAG = "previous"
 const STARTED_AT_TAG = "started_at"
 
+// safe for concurrent use by actions running in parallel
 type Storage struct {
+	mu          sync.RWMutex
 	name        string
 	storageType types.StorageType
 	entry       map[string]*Entry
+	// plan of completion storages
+	steps []*step
+	// tagged entries are also saved here, if set
+	store *Store
 
 	OnEventCallback func(event events.DisplayEvent)
 }
@@ -49,6 +54,9 @@ func NewStorage(name string, storageType types.StorageType,
 }
 
 func (s *Storage) SortedEntries() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	entries := make([]*Entry, 0, len(s.entry))
 	for _, entry := range s.entry {
 		entries = append(entries, entry)
@@ -64,6 +72,9 @@ func (s *Storage) GetName() string {
 }
 
 func (s *Storage) GetEntries() []*Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	values := []*Entry{}
 	for _, entry := range s.entry {
 		values = append(values, entry)
@@ -71,25 +82,47 @@ func (s *Storage) GetEntries() []*Entry {
 	return values
 }
 
+// copy of the entries by key
 func (s *Storage) GetEntryList() map[string]*Entry {
-	return s.entry
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make(map[string]*Entry, len(s.entry))
+	for key, entry := range s.entry {
+		entries[key] = entry
+	}
+	return entries
 }
 
 func (s *Storage) GetEntry(key string) (*Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	e, found := s.entry[key]
 	return e, found
 }
 
 func (s *Storage) IsEmpty() bool {
-	return len(s.entry) == 0
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.entry) == 0 && len(s.steps) == 0
 }
 
 func (s *Storage) GetStorageType() types.StorageType {
 	return s.storageType
 }
 
-func (s *Storage) GetStartedAt() time.Time {
-	return s.entry[STARTED_AT_TAG].Time
+// for timer storages, false if the timer was not started
+func (s *Storage) GetStartedAt() (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inner, found := s.entry[STARTED_AT_TAG]
+	if !found {
+		return time.Time{}, false
+	}
+	return inner.Time, true
 }
 
 func (s *Storage) OnEvent(event events.DisplayEvent) {
@@ -97,82 +130,71 @@ func (s *Storage) OnEvent(event events.DisplayEvent) {
 }
 
 func (s *Storage) AddData(key, data string) {
+	s.mu.Lock()
 	s.entry[key] = NewEntry(data)
-	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, key, nil, &data))
-}
+	s.mu.Unlock()
 
-func (s *Storage) AddTagged(key, data string) {
-	s.entry[key] = NewEntry(data)
 	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, key, nil, &data))
 }
 
-func (s *Storage) DelTagged(key string) {
-	if old, exists := s.entry[key]; exists {
-		delete(s.entry, key)
-		s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, key, &old.Data, nil))
+// loads the entries saved by the previous runs, the next tagged changes are saved to the store
+func (s *Storage) Persist(store *Store) error {
+	records, err := store.Load()
+	if err != nil {
+		return err
 	}
-}
-
-func (s *Storage) GetTagged(key string) string {
-	inner := s.entry[key]
-	return inner.Data
-}
 
-// for planning tasks
-func (s *Storage) AddCompletion(data string) {
-	var keys []string
-	for key := range s.entry {
-		keys = append(keys, key)
-	}
-
-	lastKey := keys[len(keys)-1]
-	lastValue := s.entry[lastKey]
-	lastValue.Data = data
-	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, lastKey, nil, &data))
-}
-
-func (s *Storage) DelCompletion(pos int) {
-	tag := strconv.Itoa(pos)
-	if old, exists := s.entry[tag]; exists {
-		delete(s.entry, tag)
-		s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, tag, &old.Data, nil))
+	s.mu.Lock()
+	for key, record := range records {
+		s.entry[key] = &Entry{
+			Time: record.Time,
+			Data: record.Data,
+		}
 	}
+	s.store = store
+	s.mu.Unlock()
+	return nil
 }
 
-func (s *Storage) SetComplete(pos int) bool {
-	tag := strconv.Itoa(pos)
-	if entry, exists := s.entry[tag]; exists {
-		prev := entry.Complete
-		entry.Complete = true
+func (s *Storage) AddTagged(key, data string) {
+	s.mu.Lock()
+	s.entry[key] = NewEntry(data)
+	store := s.store
+	s.mu.Unlock()
 
-		prevState := "incomplete"
-		if prev {
-			prevState = "complete"
+	if store != nil {
+		if err := store.Put(key, data); err != nil {
+			log.Printf("saving %s of %s: %s", key, s.name, err.Error())
 		}
-		comp := "complete"
-		s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, tag, &prevState, &comp))
-		return prev
 	}
 
-	return false
+	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, key, nil, &data))
 }
 
-func (s *Storage) SetInComplete(pos int) bool {
-	tag := strconv.Itoa(pos)
-	if entry, exists := s.entry[tag]; exists {
-		prev := entry.Complete
-		entry.Complete = false
-
-		prevState := "incomplete"
-		if prev {
-			prevState = "complete"
+func (s *Storage) DelTagged(key string) {
+	s.mu.Lock()
+	old, exists := s.entry[key]
+	delete(s.entry, key)
+	store := s.store
+	s.mu.Unlock()
+
+	if store != nil && exists {
+		if err := store.Delete(key); err != nil {
+			log.Printf("deleting %s of %s: %s", key, s.name, err.Error())
 		}
-		comp := "incomplete"
-		s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, tag, &prevState, &comp))
-		return prev
 	}
 
-	return false
+	if exists {
+		s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, key, &old.Data, nil))
+	}
+}
+
+func (s *Storage) GetTagged(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inner := s.entry[key]
+	return inner.Data
 }
 
 func (s *Storage) SetCurrent(data string) {
@@ -180,6 +202,7 @@ func (s *Storage) SetCurrent(data string) {
 		panic("storage type must be CurrentPrevious")
 	}
 
+	s.mu.Lock()
 	oldCurrent, exists := s.entry[CURRENT_TAG]
 	s.entry[CURRENT_TAG] = NewEntry(data)
 
@@ -188,10 +211,16 @@ func (s *Storage) SetCurrent(data string) {
 		prev = oldCurrent.Data
 		s.entry[PREVIOUS_TAG] = oldCurrent
 	}
+	s.mu.Unlock()
+
 	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, CURRENT_TAG, &prev, &data))
 }
 
 func (s *Storage) Clear() {
+	s.mu.Lock()
 	s.entry = make(map[string]*Entry, 0)
+	s.steps = nil
+	s.mu.Unlock()
+
 	s.OnEvent(events.NewStorageUpdateEvent(s.name, s.storageType, "", nil, nil))
 }
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/runetale/notch/events"
+	"github.com/runetale/notch/types"
+)
+
+func noEvent(events.DisplayEvent) {}
+
+func Test_ConcurrentTagged(t *testing.T) {
+	s := NewStorage("memories", types.TAGGED, noEvent)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("host-%d", i)
+			s.AddTagged(key, "up")
+			s.GetEntryList()
+			if i%2 == 0 {
+				s.DelTagged(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(s.GetEntryList()); n != 25 {
+		t.Fatalf("expected 25 entries, got %d", n)
+	}
+}
+
+func steps(s *Storage) string {
+	out := []string{}
+	for _, step := range s.GetSteps() {
+		done := ""
+		if step.Status == DONE {
+			done = "*"
+		}
+		out = append(out, fmt.Sprintf("%s.%s%s", step.Path, step.Data, done))
+	}
+	return strings.Join(out, " ")
+}
+
+func Test_Completions(t *testing.T) {
+	s := NewStorage("plan", types.COMPLETION, noEvent)
+	s.AddCompletion("scan")
+	s.AddCompletion("exploit")
+	s.AddCompletion("report")
+
+	if err := s.InsertCompletion(2, "enumerate"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertCompletion(5, "cleanup"); err != nil {
+		t.Fatal(err)
+	}
+	if got := steps(s); got != "1.scan 2.enumerate 3.exploit 4.report 5.cleanup" {
+		t.Fatalf("unexpected steps after insert: %s", got)
+	}
+
+	if err := s.SetComplete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MoveCompletion(5, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MoveCompletion(2, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := steps(s); got != "1.cleanup 2.enumerate 3.exploit 4.scan* 5.report" {
+		t.Fatalf("unexpected steps after move: %s", got)
+	}
+
+	if err := s.RenameCompletion(3, "exploit ssh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DelCompletion(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetInComplete(3); err != nil {
+		t.Fatal(err)
+	}
+	if got := steps(s); got != "1.enumerate 2.exploit ssh 3.scan 4.report" {
+		t.Fatalf("unexpected steps after delete: %s", got)
+	}
+
+	for _, err := range []error{
+		s.InsertCompletion(6, "late"),
+		s.DelCompletion(0),
+		s.MoveCompletion(1, 5),
+		s.RenameCompletion(9, "none"),
+		s.SetComplete(5),
+	} {
+		if err == nil {
+			t.Fatal("expected an invalid position")
+		}
+	}
+
+	s.Clear()
+	if !s.IsEmpty() || steps(s) != "" {
+		t.Fatal("plan was not cleared")
+	}
+}
+
+func Test_PlanTree(t *testing.T) {
+	s := NewStorage("plan", types.COMPLETION, noEvent)
+	s.AddCompletion("recon")
+	s.AddCompletion("exploitation")
+	s.AddCompletion("report")
+
+	for _, sub := range []string{"ssh brute force", "privilege escalation"} {
+		if _, err := s.AddStep("2", sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if path, err := s.AddStep("2.2", "check sudo"); err != nil || path != "2.2.1" {
+		t.Fatalf("unexpected path %s %v", path, err)
+	}
+	if err := s.InsertStep("2.1", "enumerate users"); err != nil {
+		t.Fatal(err)
+	}
+	if got := steps(s); got != "1.recon 2.exploitation 2.1.enumerate users 2.2.ssh brute force 2.3.privilege escalation 2.3.1.check sudo 3.report" {
+		t.Fatalf("unexpected steps %s", got)
+	}
+
+	// the report waits for the exploitation, the brute force for the recon
+	if err := s.AddDependency("3", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddDependency("2.2", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// the recon can't wait for the steps waiting for it, even through their sub steps
+	for _, bad := range [][2]string{{"2", "3"}, {"2.1", "2"}, {"2", "2.3.1"}, {"1", "1"}, {"1", "2"}, {"1", "3"}} {
+		if err := s.AddDependency(bad[0], bad[1]); err == nil {
+			t.Fatalf("expected %s waiting for %s to be refused", bad[0], bad[1])
+		}
+	}
+	if err := s.SetStepStatus("2", DONE); err == nil {
+		t.Fatal("expected the status of a parent step to be refused")
+	}
+
+	// a sub step waits for the dependencies of its parent
+	cycle := NewStorage("plan", types.COMPLETION, noEvent)
+	cycle.AddCompletion("recon")
+	cycle.AddCompletion("exploitation")
+	cycle.AddStep("1", "nmap")
+	if err := cycle.AddDependency("1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cycle.AddDependency("2", "1.1"); err == nil {
+		t.Fatal("expected 2 waiting for 1.1 to be refused, 1.1 waits for 2 through its parent")
+	}
+	// nor moved under the step it waits for, or the step under it
+	for _, bad := range [][2]string{{"1", "2.1"}, {"2", "1.2"}, {"2", "1.1.1"}} {
+		if err := cycle.MoveStep(bad[0], bad[1]); err == nil {
+			t.Fatalf("expected the move of %s to %s to be refused", bad[0], bad[1])
+		}
+		if got := steps(cycle); got != "1.recon 1.1.nmap 2.exploitation" {
+			t.Fatalf("the plan was changed by a refused move %s", got)
+		}
+	}
+
+	s.SetStepStatus("2.1", DONE)
+	s.SetStepStatus("2.3.1", BLOCKED)
+	view := map[string]*Step{}
+	for _, step := range s.GetSteps() {
+		view[step.Path] = step
+	}
+	if view["2"].Status != IN_PROGRESS || view["2.3"].Status != BLOCKED || view["1"].Status != PENDING {
+		t.Fatalf("unexpected derived status %s %s", view["2"].Status, view["2.3"].Status)
+	}
+	if !view["2.2"].Waiting || !view["3"].Waiting || view["2.1"].Waiting || !view["1"].Actionable() || view["2.2"].Actionable() {
+		t.Fatal("unexpected waiting steps")
+	}
+
+	// dependencies follow the steps when they move and go away with them
+	if err := s.MoveStep("1", "3"); err != nil {
+		t.Fatal(err)
+	}
+	view = map[string]*Step{}
+	for _, step := range s.GetSteps() {
+		view[step.Path] = step
+	}
+	if view["3"].Data != "recon" || view["1.2"].Depends[0] != "3" || view["2"].Depends[0] != "1" {
+		t.Fatalf("unexpected dependencies after move %+v %+v", view["1.2"], view["2"])
+	}
+	if err := s.MoveStep("1", "4"); err == nil || s.GetSteps()[0].Data != "exploitation" {
+		t.Fatal("expected an invalid move to leave the plan as it was")
+	}
+	if err := s.DelStep("1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetSteps(); len(got) != 2 || len(got[0].Depends) != 0 || got[0].Waiting {
+		t.Fatalf("dependency on a removed step was kept %+v", got[0])
+	}
+}
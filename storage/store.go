@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// a lock older than this is left by a crashed run
+const staleLock = 30 * time.Second
+
+const lockTimeout = 5 * time.Second
+
+var bankName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// an entry kept between runs, with the run that saved it
+type Record struct {
+	Data    string    `json:"data"`
+	Session string    `json:"session"`
+	Time    time.Time `json:"time"`
+}
+
+// file backed entries of a storage, shared by the runs using the same bank.
+// every change is merged into the file under a lock, so runs at the same time keep each other's entries
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	session string
+}
+
+// the bank is a file of the folder, created on the first change
+func OpenStore(dir, bank, session string) (*Store, error) {
+	name := bankName.ReplaceAllString(bank, "_")
+	if name == "" || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid memory bank name '%s'", bank)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		path:    filepath.Join(dir, name+".json"),
+		session: session,
+	}, nil
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) Load() (map[string]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+func (s *Store) Put(key, data string) error {
+	return s.update(func(records map[string]*Record) {
+		records[key] = &Record{
+			Data:    data,
+			Session: s.session,
+			Time:    time.Now(),
+		}
+	})
+}
+
+func (s *Store) Delete(key string) error {
+	return s.update(func(records map[string]*Record) {
+		delete(records, key)
+	})
+}
+
+func (s *Store) update(change func(records map[string]*Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := s.read()
+	if err != nil {
+		return err
+	}
+	change(records)
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	// replaced at once, a reader never sees a partial file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *Store) read() (map[string]*Record, error) {
+	records := map[string]*Record{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("memory bank %s: %s", s.path, err.Error())
+	}
+	return records, nil
+}
+
+// a lock file shared with the other processes
+func (s *Store) lock() (func(), error) {
+	path := s.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("memory bank %s is locked", s.path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/runetale/notch/types"
+)
+
+func Test_StoreAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := OpenStore(dir, "weekly scan", "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStorage("memories", types.TAGGED, noEvent)
+	if err := s.Persist(first); err != nil {
+		t.Fatal(err)
+	}
+	s.AddTagged("open_ports", "22, 80")
+	s.AddTagged("stale", "remove me")
+	s.DelTagged("stale")
+
+	// the next run starts with the memories of the first one
+	second, err := OpenStore(dir, "weekly scan", "run-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := NewStorage("memories", types.TAGGED, noEvent)
+	if err := next.Persist(second); err != nil {
+		t.Fatal(err)
+	}
+	if entry, found := next.GetEntry("open_ports"); !found || entry.Data != "22, 80" {
+		t.Fatalf("memory was not loaded %v", entry)
+	}
+	if _, found := next.GetEntry("stale"); found {
+		t.Fatal("deleted memory was loaded")
+	}
+
+	records, err := second.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records["open_ports"].Session != "run-1" || records["open_ports"].Time.IsZero() {
+		t.Fatalf("missing provenance %+v", records["open_ports"])
+	}
+}
+
+func Test_StoreConcurrentRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	// two runs on the same bank keep each other's entries
+	var wg sync.WaitGroup
+	for run := 0; run < 2; run++ {
+		store, err := OpenStore(dir, "shared", fmt.Sprintf("run-%d", run))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(run, i int) {
+				defer wg.Done()
+				if err := store.Put(fmt.Sprintf("%d-%d", run, i), "found"); err != nil {
+					t.Error(err)
+				}
+			}(run, i)
+		}
+	}
+	wg.Wait()
+
+	store, _ := OpenStore(dir, "shared", "check")
+	records, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 40 {
+		t.Fatalf("expected 40 memories, got %d", len(records))
+	}
+}
+
+func Test_OpenStoreName(t *testing.T) {
+	store, err := OpenStore(t.TempDir(), "../../etc/passwd", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base := store.Path()[len(store.Path())-len("_etc_passwd.json"):]; base != "_etc_passwd.json" {
+		t.Fatalf("bank escaped its folder %s", store.Path())
+	}
+	if _, err := OpenStore(t.TempDir(), "..", "run"); err == nil {
+		t.Fatal("expected an invalid bank name")
+	}
+}
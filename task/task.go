@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,17 +23,249 @@ type Task struct {
 	name         string         `yaml:"-"`
 	folder       string         `yaml:"-"`
 	timeout      *time.Duration `yaml:"-"`
+	embedder     Embedder       `yaml:"-"`
+	session      string         `yaml:"-"`
 	Using        []*string      `yaml:"using"`
 	SystemPrompt *string        `yaml:"system_prompt"`
 	Prompt       *string        `yaml:"prompt"`
 	Guidance     []string       `yaml:"-"`
 	Functions    []*Function    `yaml:"functions"`
+	Loop         *LoopDetection `yaml:"loop_detection"`
+	Hooks        []*Hook        `yaml:"hooks"`
+	Budget       *Budget        `yaml:"budget"`
+	History      *History       `yaml:"history"`
+	Summary      *Summary       `yaml:"summary"`
+	DryRun       *DryRun        `yaml:"dry_run"`
+	Plan         *PlanExecute   `yaml:"plan_and_execute"`
+	Reflection   *Reflection    `yaml:"reflection"`
+	Reasoning    *Reasoning     `yaml:"reasoning"`
+	Filesystem   *Filesystem    `yaml:"filesystem"`
+	Shell        *Shell         `yaml:"shell"`
+	HTTP         *HTTP          `yaml:"http"`
+	Rag          *Rag           `yaml:"rag"`
+	Memory       *Memory        `yaml:"memory"`
+	// parallel safe invocations executed at once
+	Concurrency uint        `yaml:"concurrency"`
+	ErrorPolicy ErrorPolicy `yaml:"error_policy"`
+}
+
+// what happens to the remaining invocations of a response after one fails
+type ErrorPolicy string
+
+const (
+	// skip every remaining invocation
+	ERROR_STOP ErrorPolicy = "stop"
+	// execute every remaining invocation
+	ERROR_CONTINUE ErrorPolicy = "continue"
+	// skip the remaining invocations of the failed actions and the sequential ones,
+	// independent parallel safe invocations are still executed
+	ERROR_SKIP_DEPENDENT ErrorPolicy = "skip_dependent"
+)
+
+// the model writes a plan first, then works on one step at a time.
+// requires the planning namespace
+type PlanExecute struct {
+	Enable bool `yaml:"enable"`
+	// consecutive failed steps on the same plan step before re-planning
+	MaxFailures uint `yaml:"max_failures"`
+}
+
+// filesystem namespace, every path is confined to the root
+type Filesystem struct {
+	// the current directory if empty
+	Root string `yaml:"root"`
+	// bytes returned by read_file
+	MaxRead int64 `yaml:"max_read"`
+	// size of a written file
+	MaxWrite int64 `yaml:"max_write"`
+}
+
+// shell namespace
+type Shell struct {
+	// commands run in long lived sessions keeping the directory and the exported variables
+	Persistent bool `yaml:"persistent"`
+	// program of the sessions, /bin/sh if empty
+	Program string `yaml:"program"`
+	// seconds a command of a session may run before the session is restarted, 0 is the no limit
+	Timeout uint `yaml:"timeout"`
+}
+
+// http namespace, requests are only sent to the allowed hosts
+type HTTP struct {
+	// host names, *.domain wildcards or CIDR ranges, every host if empty
+	Allow []string `yaml:"allow"`
+	// bytes of the response shown to the model
+	MaxResponse int `yaml:"max_response"`
+	// seconds
+	Timeout uint `yaml:"timeout"`
+	// skips the verification of tls certificates
+	Insecure bool `yaml:"insecure"`
+}
+
+// rag namespace, the documents of the folder are searched by the model
+type Rag struct {
+	// folder of the documents, relative to the task file
+	Path string `yaml:"path"`
+	// generator string of the embedding model, e.g. openai://text-embedding-3-small,
+	// the documents are ranked with bm25 if empty
+	Embedder string `yaml:"embedder"`
+	// characters of a chunk
+	ChunkSize int `yaml:"chunk_size"`
+	// chunks returned by a search
+	TopK int `yaml:"top_k"`
+	// file keeping the embeddings between runs, relative to the task file
+	Cache string `yaml:"cache"`
+}
+
+// memory namespace
+type Memory struct {
+	// only the keys are shown in the prompt past this number of memories, 0 always shows everything
+	KeysOnlyAfter int `yaml:"keys_only_after"`
+	// the memories are kept between runs in the bank of the task
+	Persist bool `yaml:"persist"`
+	// memory bank shared by the tasks using the same name, the task name if empty
+	Bank string `yaml:"bank"`
+	// folder of the banks, ~/.notch/memory if empty
+	Dir string `yaml:"dir"`
+}
+
+// embeds texts for the rag namespace, set by the caller since it needs an llm client
+type Embedder interface {
+	Name() string
+	Embed(texts []string) ([][]float32, error)
+}
+
+type ReasoningPolicy string
+
+const (
+	// the reasoning is only recorded
+	REASONING_NONE ReasoningPolicy = "none"
+	// the reasoning of the latest response is sent back to the model
+	REASONING_LAST ReasoningPolicy = "last"
+	// every reasoning in the history window is sent back to the model
+	REASONING_ALL ReasoningPolicy = "all"
+)
+
+// text of the responses around the invocations, e.g. <think> blocks
+type Reasoning struct {
+	History ReasoningPolicy `yaml:"history"`
+	// json lines file the reasoning of every response is appended to
+	Export string `yaml:"export"`
+}
+
+// critique turns where the model analyses its failures before acting again
+type Reflection struct {
+	Enable bool `yaml:"enable"`
+	// consecutive errored or timed out executions, 0 disables the trigger
+	AfterFailures uint `yaml:"after_failures"`
+	// steps between two reflections, 0 disables the cadence
+	Every uint `yaml:"every"`
+	// replaces the default critique prompt
+	Prompt string `yaml:"prompt"`
+}
+
+// actions with effects outside the engine are not executed, a synthetic result is returned
+type DryRun struct {
+	Enable bool `yaml:"enable"`
+	// canned, stdin or model
+	Simulate string `yaml:"simulate"`
+	// canned results by action name
+	Results map[string]string `yaml:"results"`
+	// read only actions are executed unless set
+	SimulateReads bool `yaml:"simulate_reads"`
+	// generator string of the model simulation, the main generator is used if empty
+	Generator string `yaml:"generator"`
+}
+
+// condense the executions evicted from the history window into a running summary
+type Summary struct {
+	Enable bool `yaml:"enable"`
+	// number of evicted executions condensed at once
+	Batch uint `yaml:"batch"`
+	// generator string of a cheaper model, the main generator is used if empty
+	Generator string `yaml:"generator"`
+}
+
+type HistoryStrategy string
+
+const (
+	// the most recent executions
+	HISTORY_RECENT HistoryStrategy = "recent"
+	// the first executions and the most recent ones
+	HISTORY_PINNED HistoryStrategy = "pinned"
+	// the most recent executions and every older error
+	HISTORY_ERRORS HistoryStrategy = "errors"
+	// the most recent executions and a summary of the older ones
+	HISTORY_SUMMARIZE HistoryStrategy = "summarize"
+)
+
+type History struct {
+	Strategy HistoryStrategy `yaml:"strategy"`
+	// executions kept in the chat history
+	Max uint `yaml:"max"`
+	// first executions always kept by the pinned strategy
+	Pinned uint `yaml:"pinned"`
+}
+
+// per run limits, zero values are unlimited
+type Budget struct {
+	// elapsed wall-clock time, e.g. 30m
+	Time time.Duration `yaml:"time"`
+	// prompt and completion tokens
+	Tokens uint64 `yaml:"tokens"`
+	// estimated spend in USD
+	Cost float64 `yaml:"cost"`
+	// USD per million tokens, known model prices are used if not set
+	InputPrice  float64 `yaml:"input_price"`
+	OutputPrice float64 `yaml:"output_price"`
+	// maximum executions per action name
+	Actions map[string]uint `yaml:"actions"`
+	// 0 to 1, fraction of a budget after which the model is warned
+	WarnAt float64 `yaml:"warn_at"`
+}
+
+// external command run around action execution, it gets json on stdin
+type Hook struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"`
+	// before, after or both
+	Stage string `yaml:"stage"`
+	// only run for these actions, all actions if empty
+	Actions []string `yaml:"actions"`
+	// seconds
+	Timeout int `yaml:"timeout"`
+}
+
+type LoopStrategy string
+
+const (
+	// clear the plan and ask for a different approach
+	LOOP_REPLAN LoopStrategy = "replan"
+	// stop the run
+	LOOP_STOP LoopStrategy = "stop"
+)
+
+type LoopDetection struct {
+	Disable bool `yaml:"disable"`
+	// number of latest invocations inspected
+	Window int `yaml:"window"`
+	// occurrences of the same invocation reported as a loop
+	MinRepeats int `yaml:"min_repeats"`
+	// 0 to 1, invocations at or above this ratio are considered the same,
+	// 1 by default so that e.g. a sweep over hosts is not a loop
+	Similarity float64 `yaml:"similarity"`
+	// consecutive detections before OnThreshold is applied
+	Threshold   int          `yaml:"threshold"`
+	OnThreshold LoopStrategy `yaml:"on_threshold"`
 }
 
 type Function struct {
 	Name        string   `yaml:"name"`
 	Description string   `yaml:"description"`
 	Actions     []Action `yaml:"actions"`
+	// the actions have no side effects and their invocations may run concurrently,
+	// e.g. scans of several hosts
+	ParallelSafe bool `yaml:"parallel_safe"`
 }
 
 type Action struct {
@@ -41,6 +274,8 @@ type Action struct {
 	Tool           string `yaml:"tool"`
 	MaxShownOutput int    `yaml:"max_shown_output"`
 	ExamplePayload string `yaml:"example_payload,omitempty"`
+	// seconds the tool may run before it is stopped, 10 minutes if 0
+	Timeout uint `yaml:"timeout"`
 }
 
 func GetFromPath(path string) (*Task, error) {
@@ -126,7 +361,381 @@ func (t *Task) GetSystemPrompt() string {
 }
 
 func (t *Task) GetMaxHistory() uint {
-	return 50
+	return t.GetHistory().Max
+}
+
+// history window settings, unset values are filled with defaults
+func (t *Task) GetHistory() History {
+	history := History{}
+	if t.History != nil {
+		history = *t.History
+	}
+	if history.Strategy == "" {
+		history.Strategy = HISTORY_RECENT
+	}
+	if history.Max == 0 {
+		history.Max = 50
+	}
+	if history.Strategy == HISTORY_PINNED && history.Pinned == 0 {
+		history.Pinned = 5
+	}
+	if history.Pinned > history.Max {
+		history.Pinned = history.Max
+	}
+	return history
+}
+
+// loop detection settings, unset values are filled with defaults
+func (t *Task) GetLoopDetection() LoopDetection {
+	loop := LoopDetection{}
+	if t.Loop != nil {
+		loop = *t.Loop
+	}
+	if loop.Window <= 0 {
+		loop.Window = 8
+	}
+	if loop.MinRepeats <= 1 {
+		loop.MinRepeats = 3
+	}
+	if loop.Similarity <= 0 || loop.Similarity > 1 {
+		loop.Similarity = 1
+	}
+	if loop.Threshold <= 0 {
+		loop.Threshold = 3
+	}
+	if loop.OnThreshold == "" {
+		loop.OnThreshold = LOOP_REPLAN
+	}
+	return loop
+}
+
+// run budget, unset values are unlimited
+func (t *Task) GetBudget() Budget {
+	budget := Budget{}
+	if t.Budget != nil {
+		budget = *t.Budget
+	}
+	if budget.WarnAt <= 0 || budget.WarnAt > 1 {
+		budget.WarnAt = 0.8
+	}
+	return budget
+}
+
+// overrides the task budget with the values set, e.g. from the command line
+func (t *Task) SetBudget(override Budget) {
+	if t.Budget == nil {
+		t.Budget = &Budget{}
+	}
+	if override.Time > 0 {
+		t.Budget.Time = override.Time
+	}
+	if override.Tokens > 0 {
+		t.Budget.Tokens = override.Tokens
+	}
+	if override.Cost > 0 {
+		t.Budget.Cost = override.Cost
+	}
+	if override.InputPrice > 0 {
+		t.Budget.InputPrice = override.InputPrice
+	}
+	if override.OutputPrice > 0 {
+		t.Budget.OutputPrice = override.OutputPrice
+	}
+	if override.WarnAt > 0 {
+		t.Budget.WarnAt = override.WarnAt
+	}
+	for name, max := range override.Actions {
+		if t.Budget.Actions == nil {
+			t.Budget.Actions = make(map[string]uint, 0)
+		}
+		t.Budget.Actions[name] = max
+	}
+}
+
+// summary settings, unset values are filled with defaults
+func (t *Task) GetSummary() Summary {
+	summary := Summary{}
+	if t.Summary != nil {
+		summary = *t.Summary
+	}
+	if summary.Batch == 0 {
+		summary.Batch = 5
+	}
+	return summary
+}
+
+// enables the summary, e.g. from the command line
+func (t *Task) SetSummary(generator string) {
+	if t.Summary == nil {
+		t.Summary = &Summary{}
+	}
+	t.Summary.Enable = true
+	if generator != "" {
+		t.Summary.Generator = generator
+	}
+}
+
+// filesystem settings, unset values are filled with defaults
+func (t *Task) GetFilesystem() Filesystem {
+	fs := Filesystem{}
+	if t.Filesystem != nil {
+		fs = *t.Filesystem
+	}
+	if fs.Root == "" {
+		fs.Root = "."
+	}
+	if fs.MaxRead <= 0 {
+		fs.MaxRead = 64 * 1024
+	}
+	if fs.MaxWrite <= 0 {
+		fs.MaxWrite = 1024 * 1024
+	}
+	return fs
+}
+
+// confines the filesystem namespace, e.g. from the command line
+func (t *Task) SetFilesystemRoot(root string) {
+	if t.Filesystem == nil {
+		t.Filesystem = &Filesystem{}
+	}
+	t.Filesystem.Root = root
+}
+
+// shell settings, unset values are filled with defaults
+func (t *Task) GetShell() Shell {
+	sh := Shell{}
+	if t.Shell != nil {
+		sh = *t.Shell
+	}
+	if sh.Program == "" {
+		sh.Program = "/bin/sh"
+	}
+	return sh
+}
+
+// runs the commands in persistent sessions, e.g. from the command line
+func (t *Task) SetShellPersistent(persistent bool) {
+	if t.Shell == nil {
+		t.Shell = &Shell{}
+	}
+	t.Shell.Persistent = persistent
+}
+
+// http settings, unset values are filled with defaults
+func (t *Task) GetHTTP() HTTP {
+	h := HTTP{}
+	if t.HTTP != nil {
+		h = *t.HTTP
+	}
+	if h.MaxResponse <= 0 {
+		h.MaxResponse = 16 * 1024
+	}
+	if h.Timeout == 0 {
+		h.Timeout = 30
+	}
+	return h
+}
+
+// restricts the hosts of the http namespace, e.g. from the command line
+func (t *Task) SetHTTPAllow(allow []string) {
+	if t.HTTP == nil {
+		t.HTTP = &HTTP{}
+	}
+	t.HTTP.Allow = allow
+}
+
+// rag settings, unset values are filled with defaults and paths are resolved from the task folder
+func (t *Task) GetRag() Rag {
+	rag := Rag{}
+	if t.Rag != nil {
+		rag = *t.Rag
+	}
+	if rag.ChunkSize <= 0 {
+		rag.ChunkSize = 1000
+	}
+	if rag.TopK <= 0 {
+		rag.TopK = 5
+	}
+	if rag.Path != "" && !filepath.IsAbs(rag.Path) {
+		rag.Path = filepath.Join(t.GetDir(), rag.Path)
+	}
+	if rag.Cache != "" && !filepath.IsAbs(rag.Cache) {
+		rag.Cache = filepath.Join(t.GetDir(), rag.Cache)
+	}
+	return rag
+}
+
+// memory settings, a bank enables the persistence
+func (t *Task) GetMemory() Memory {
+	memory := Memory{}
+	if t.Memory != nil {
+		memory = *t.Memory
+	}
+	if memory.Bank != "" {
+		memory.Persist = true
+	}
+	if memory.Persist && memory.Bank == "" {
+		memory.Bank = t.GetName()
+	}
+	if memory.Dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			memory.Dir = filepath.Join(home, ".notch", "memory")
+		} else {
+			memory.Dir = filepath.Join(".notch", "memory")
+		}
+	}
+	return memory
+}
+
+// keeps the memories in the bank between runs
+func (t *Task) SetMemoryBank(bank string) {
+	if t.Memory == nil {
+		t.Memory = &Memory{}
+	}
+	t.Memory.Bank = bank
+}
+
+// identifies the run, shared by its sub agents
+func (t *Task) GetSession() string {
+	if t.session == "" {
+		t.session = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+	}
+	return t.session
+}
+
+func (t *Task) SetMemoryKeysOnlyAfter(n int) {
+	if t.Memory == nil {
+		t.Memory = &Memory{}
+	}
+	t.Memory.KeysOnlyAfter = n
+}
+
+func (t *Task) SetEmbedder(embedder Embedder) {
+	t.embedder = embedder
+}
+
+// nil if the documents are ranked with bm25
+func (t *Task) GetEmbedder() Embedder {
+	return t.embedder
+}
+
+func (t *Task) GetReasoning() Reasoning {
+	reasoning := Reasoning{}
+	if t.Reasoning != nil {
+		reasoning = *t.Reasoning
+	}
+	switch reasoning.History {
+	case REASONING_LAST, REASONING_ALL:
+	default:
+		reasoning.History = REASONING_NONE
+	}
+	return reasoning
+}
+
+// overrides the task values, e.g. from the command line. empty values keep the task values
+func (t *Task) SetReasoning(history ReasoningPolicy, export string) {
+	if t.Reasoning == nil {
+		t.Reasoning = &Reasoning{}
+	}
+	if history != "" {
+		t.Reasoning.History = history
+	}
+	if export != "" {
+		t.Reasoning.Export = export
+	}
+}
+
+// reflection settings, reflects after 3 failures if no trigger is set
+func (t *Task) GetReflection() Reflection {
+	reflection := Reflection{}
+	if t.Reflection != nil {
+		reflection = *t.Reflection
+	}
+	if reflection.AfterFailures == 0 && reflection.Every == 0 {
+		reflection.AfterFailures = 3
+	}
+	return reflection
+}
+
+// enables the reflection, e.g. from the command line. zero values keep the task values
+func (t *Task) SetReflection(afterFailures, every uint) {
+	if t.Reflection == nil {
+		t.Reflection = &Reflection{}
+	}
+	t.Reflection.Enable = true
+	if afterFailures > 0 {
+		t.Reflection.AfterFailures = afterFailures
+	}
+	if every > 0 {
+		t.Reflection.Every = every
+	}
+}
+
+// plan and execute settings, unset values are filled with defaults
+func (t *Task) GetPlanExecute() PlanExecute {
+	plan := PlanExecute{}
+	if t.Plan != nil {
+		plan = *t.Plan
+	}
+	if plan.MaxFailures == 0 {
+		plan.MaxFailures = 3
+	}
+	return plan
+}
+
+// enables the plan and execute mode, e.g. from the command line
+func (t *Task) SetPlanExecute() {
+	if t.Plan == nil {
+		t.Plan = &PlanExecute{}
+	}
+	t.Plan.Enable = true
+}
+
+// dry run settings, unset values are filled with defaults
+func (t *Task) GetDryRun() DryRun {
+	dryRun := DryRun{}
+	if t.DryRun != nil {
+		dryRun = *t.DryRun
+	}
+	if dryRun.Simulate == "" {
+		dryRun.Simulate = "canned"
+	}
+	return dryRun
+}
+
+// enables the dry run, e.g. from the command line
+func (t *Task) SetDryRun(simulate string, simulateReads bool) {
+	if t.DryRun == nil {
+		t.DryRun = &DryRun{}
+	}
+	t.DryRun.Enable = true
+	if simulate != "" {
+		t.DryRun.Simulate = simulate
+	}
+	t.DryRun.SimulateReads = t.DryRun.SimulateReads || simulateReads
+}
+
+func (t *Task) GetConcurrency() uint {
+	if t.Concurrency == 0 {
+		return 4
+	}
+	return t.Concurrency
+}
+
+func (t *Task) GetErrorPolicy() ErrorPolicy {
+	switch t.ErrorPolicy {
+	case ERROR_CONTINUE, ERROR_SKIP_DEPENDENT:
+		return t.ErrorPolicy
+	}
+	return ERROR_STOP
+}
+
+func (t *Task) SetErrorPolicy(policy ErrorPolicy) {
+	t.ErrorPolicy = policy
+}
+
+func (t *Task) SetConcurrency(concurrency uint) {
+	t.Concurrency = concurrency
 }
 
 func (t *Task) GetGuidance() []string {
@@ -181,8 +790,9 @@ func (t *Task) ParseVariableExpr(expr string) (string, string, error) {
 		return varName, varDefault, nil
 	}
 
-	// user input
+	// user input, kept in the environment so it is asked once
 	userInput := t.GetUserInput(fmt.Sprintf("\nplease set $%s: ", varName))
+	os.Setenv(varName, userInput)
 	return varName, userInput, nil
 }
 
@@ -193,6 +803,60 @@ func (t *Task) GetTimeout() *time.Duration {
 	return nil
 }
 
+func (t *Task) GetHooks() []*Hook {
+	return t.Hooks
+}
+
+// directory of the task file, hook commands run from here
+func (t *Task) GetDir() string {
+	return filepath.Dir(t.folder)
+}
+
+// task for a sub agent, with the same prompts but its own goal,
+// the given namespaces and the given functions
+func (t *Task) NewChild(prompt string, namespaces []string, functions []string) *Task {
+	using := []*string{}
+	for _, ns := range namespaces {
+		using = append(using, &ns)
+	}
+	// only the functions the sub agent is allowed to call
+	fns := []*Function{}
+	for _, fn := range t.Functions {
+		if slices.Contains(functions, fn.Name) {
+			fns = append(fns, fn)
+		}
+	}
+
+	return &Task{
+		name:         t.name,
+		folder:       t.folder,
+		timeout:      t.timeout,
+		embedder:     t.embedder,
+		session:      t.GetSession(),
+		Using:        using,
+		SystemPrompt: t.SystemPrompt,
+		Prompt:       &prompt,
+		Guidance:     t.Guidance,
+		Functions:    fns,
+		Loop:         t.Loop,
+		Hooks:        t.Hooks,
+		Budget:       t.Budget,
+		History:      t.History,
+		Summary:      t.Summary,
+		DryRun:       t.DryRun,
+		Plan:         t.Plan,
+		Reflection:   t.Reflection,
+		Reasoning:    t.Reasoning,
+		Filesystem:   t.Filesystem,
+		Shell:        t.Shell,
+		HTTP:         t.HTTP,
+		Rag:          t.Rag,
+		Memory:       t.Memory,
+		Concurrency:  t.Concurrency,
+		ErrorPolicy:  t.ErrorPolicy,
+	}
+}
+
 func (t *Task) GetName() string {
 	return t.name
 }
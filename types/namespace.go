@@ -8,7 +8,7 @@ type NamespaceType string
 // - ui interactions
 
 const (
-	FILESYSTEM NamespaceType = "filesytem"
+	FILESYSTEM NamespaceType = "filesystem"
 	GOAL       NamespaceType = "goal"
 	HTTP       NamespaceType = "http"
 	MEMORY     NamespaceType = "memory"
@@ -17,6 +17,7 @@ const (
 	RAG        NamespaceType = "rag"
 	PLANNING   NamespaceType = "planning"
 	TASKLET    NamespaceType = "tasklet"
+	AGENT      NamespaceType = "agent"
 )
 
 func GetNameSpaceValues() []NamespaceType {
@@ -30,5 +31,17 @@ func GetNameSpaceValues() []NamespaceType {
 	ns = append(ns, RAG)
 	ns = append(ns, PLANNING)
 	ns = append(ns, TASKLET)
+	ns = append(ns, AGENT)
+	return ns
+}
+
+// namespaces of a task without using, the others are enabled by the task
+func GetDefaultNameSpaceValues() []NamespaceType {
+	ns := []NamespaceType{}
+	ns = append(ns, GOAL)
+	ns = append(ns, MEMORY)
+	ns = append(ns, SHELL)
+	ns = append(ns, PLANNING)
+	ns = append(ns, TASKLET)
 	return ns
 }